@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// API key constants
+const (
+	base62Alphabet = digits + upperChars + lowerChars
+	checksumLength = 6 // 62^6 > 2^32, so any CRC32 fits
+	maxPrefixLen   = 64
+)
+
+// ErrChecksumMismatch is returned when an API key's checksum does not match
+// its body
+var ErrChecksumMismatch = errors.New("api key checksum mismatch")
+
+// castagnoliTable is the CRC32-C polynomial table
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumAlgorithm identifies the checksum appended to API keys
+type ChecksumAlgorithm uint8
+
+// Supported API key checksums
+const (
+	ChecksumCRC32 ChecksumAlgorithm = iota
+	ChecksumCRC32C
+	ChecksumNone
+)
+
+// String implements fmt.Stringer for ChecksumAlgorithm
+func (a ChecksumAlgorithm) String() string {
+	switch a {
+	case ChecksumCRC32:
+		return "crc32"
+	case ChecksumCRC32C:
+		return "crc32c"
+	case ChecksumNone:
+		return "none"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseChecksumAlgorithm parses a string into ChecksumAlgorithm
+func ParseChecksumAlgorithm(s string) (ChecksumAlgorithm, error) {
+	switch strings.ToLower(s) {
+	case "crc32":
+		return ChecksumCRC32, nil
+	case "crc32c":
+		return ChecksumCRC32C, nil
+	case "none":
+		return ChecksumNone, nil
+	default:
+		return 0, fmt.Errorf("invalid checksum algorithm: %q", s)
+	}
+}
+
+// Len returns the number of characters the checksum occupies
+func (a ChecksumAlgorithm) Len() int {
+	if a == ChecksumNone {
+		return 0
+	}
+	return checksumLength
+}
+
+// Sum returns the base62 checksum of s, left-padded with '0' to a fixed
+// width
+func (a ChecksumAlgorithm) Sum(s string) string {
+	var sum uint32
+	switch a {
+	case ChecksumCRC32:
+		sum = crc32.ChecksumIEEE([]byte(s))
+	case ChecksumCRC32C:
+		sum = crc32.Checksum([]byte(s), castagnoliTable)
+	default:
+		return ""
+	}
+
+	out := make([]byte, checksumLength)
+	for i := checksumLength - 1; i >= 0; i-- {
+		out[i] = base62Alphabet[sum%62]
+		sum /= 62
+	}
+	return string(out)
+}
+
+// VerifyAPIKey checks the trailing checksum of an API key. As in GitHub's
+// token format, the checksum covers the random body only. If prefix is
+// non-empty the key must start with it; otherwise the body is taken to start
+// after an '_', as in ghp_, or at the start of a key without one.
+func VerifyAPIKey(key, prefix string, algo ChecksumAlgorithm) error {
+	if algo == ChecksumNone {
+		return errors.New("cannot verify api keys without a checksum")
+	}
+	if prefix != "" && !strings.HasPrefix(key, prefix) {
+		return fmt.Errorf("api key does not start with %q", prefix)
+	}
+	if len(key) <= len(prefix)+algo.Len() {
+		return errors.New("api key too short")
+	}
+
+	split := len(key) - algo.Len()
+	sum := key[split:]
+	if prefix != "" {
+		if algo.Sum(key[len(prefix):split]) != sum {
+			return ErrChecksumMismatch
+		}
+		return nil
+	}
+
+	// The body may itself contain '_', so try every position a prefix could
+	// end at
+	for start := 0; start < split; start++ {
+		if (start == 0 || key[start-1] == '_') && algo.Sum(key[start:split]) == sum {
+			return nil
+		}
+	}
+	return ErrChecksumMismatch
+}
+
+// validatePrefix checks that an API key prefix is printable and short
+func validatePrefix(prefix string) error {
+	if len(prefix) > maxPrefixLen {
+		return fmt.Errorf("prefix too long: %d (max %d)", len(prefix), maxPrefixLen)
+	}
+	for i := 0; i < len(prefix); i++ {
+		if prefix[i] <= ' ' || prefix[i] > '~' {
+			return fmt.Errorf("prefix contains invalid character %q", prefix[i])
+		}
+	}
+	return nil
+}
+
+// generateAPIKey generates a prefixed API key: prefix, random body drawn
+// from the configured charset, then a checksum of the body
+func (cg *CryptoGenerator) generateAPIKey(ctx context.Context, config *GeneratorConfig) (string, error) {
+	body, err := cg.generateSecureString(ctx, config.Length, config.Charset)
+	if err != nil {
+		return "", err
+	}
+
+	return config.Prefix + body + config.Checksum.Sum(body), nil
+}
+
+// newVerifyKeyCommand creates the verify-key subcommand, which validates API
+// key checksums read from stdin
+func (app *Application) newVerifyKeyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-key",
+		Short: "Verify API key checksums offline",
+		Long: `Verify the checksums of API keys read from stdin, one per line.
+
+Keys are never accepted as arguments. The command exits non-zero if any key
+fails verification.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         app.runVerifyKey,
+	}
+
+	cmd.Flags().StringP("prefix", "", "", "Required key prefix")
+	cmd.Flags().StringP("checksum", "", "crc32", "Checksum algorithm (crc32|crc32c)")
+
+	return cmd
+}
+
+// runVerifyKey executes the verify-key subcommand
+func (app *Application) runVerifyKey(cmd *cobra.Command, args []string) error {
+	prefix, _ := cmd.Flags().GetString("prefix")
+	name, _ := cmd.Flags().GetString("checksum")
+	algo, err := ParseChecksumAlgorithm(name)
+	if err != nil {
+		return err
+	}
+
+	invalid, total, err := verifyAPIKeys(cmd.InOrStdin(), cmd.OutOrStdout(), prefix, algo)
+	if err != nil {
+		return err
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d keys failed verification", invalid, total)
+	}
+	return nil
+}
+
+// verifyAPIKeys reports the verification result of each line in r,
+// returning the number of invalid keys and the number checked
+func verifyAPIKeys(r io.Reader, w io.Writer, prefix string, algo ChecksumAlgorithm) (invalid, total int, err error) {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		key := strings.TrimSpace(scanner.Text())
+		if key == "" {
+			continue
+		}
+		total++
+
+		if err := VerifyAPIKey(key, prefix, algo); err != nil {
+			invalid++
+			fmt.Fprintf(w, "line %d: invalid (%v)\n", line, err)
+			continue
+		}
+		fmt.Fprintf(w, "line %d: ok\n", line)
+	}
+	if err := scanner.Err(); err != nil {
+		return invalid, total, fmt.Errorf("reading keys: %w", err)
+	}
+
+	return invalid, total, nil
+}
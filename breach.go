@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4" // NTLM is defined over MD4
+)
+
+// Breach screening constants
+const (
+	maxBreachAttempts = 100
+	breachReadSize    = 256 // Longer than any "HASH:COUNT" line
+)
+
+// BreachHash identifies the hash format of a breach corpus
+type BreachHash uint8
+
+// Hash formats used by the Have I Been Pwned password downloads
+const (
+	BreachSHA1 BreachHash = iota
+	BreachNTLM
+)
+
+// String implements fmt.Stringer for BreachHash
+func (h BreachHash) String() string {
+	switch h {
+	case BreachSHA1:
+		return "sha1"
+	case BreachNTLM:
+		return "ntlm"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseBreachHash parses a string into BreachHash
+func ParseBreachHash(s string) (BreachHash, error) {
+	switch strings.ToLower(s) {
+	case "sha1", "sha-1":
+		return BreachSHA1, nil
+	case "ntlm":
+		return BreachNTLM, nil
+	default:
+		return 0, fmt.Errorf("invalid breach hash: %q", s)
+	}
+}
+
+// Sum returns the upper-case hex digest of password in this format
+func (h BreachHash) Sum(password string) string {
+	switch h {
+	case BreachNTLM:
+		d := md4.New()
+		units := utf16.Encode([]rune(password))
+		buf := make([]byte, 2*len(units))
+		for i, u := range units {
+			binary.LittleEndian.PutUint16(buf[2*i:], u)
+		}
+		d.Write(buf)
+		return strings.ToUpper(hex.EncodeToString(d.Sum(nil)))
+	default:
+		sum := sha1.Sum([]byte(password))
+		return strings.ToUpper(hex.EncodeToString(sum[:]))
+	}
+}
+
+// BreachDB screens passwords against a local Have I Been Pwned hash file
+// sorted by hash, with one "HASH:COUNT" entry per line. Lookups binary
+// search the file in place, so it is never loaded into memory.
+type BreachDB struct {
+	file *os.File
+	size int64
+	hash BreachHash
+}
+
+// OpenBreachDB opens a sorted breach corpus in the given hash format
+func OpenBreachDB(path string, hash BreachHash) (*BreachDB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &BreachDB{file: f, size: info.Size(), hash: hash}, nil
+}
+
+// Close closes the underlying file
+func (db *BreachDB) Close() error {
+	return db.file.Close()
+}
+
+// Hash returns the hash format of the corpus
+func (db *BreachDB) Hash() BreachHash {
+	return db.hash
+}
+
+// Lookup returns how often password appears in the corpus (0 if absent).
+// It is safe for concurrent use.
+func (db *BreachDB) Lookup(password string) (uint64, error) {
+	target := []byte(db.hash.Sum(password))
+
+	lo, hi := int64(0), db.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		line, start, next, err := db.lineAfter(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			// No entry starts in [mid, hi)
+			hi = mid
+			continue
+		}
+
+		hash, count, _ := bytes.Cut(line, []byte(":"))
+		switch c := bytes.Compare(bytes.ToUpper(hash), target); {
+		case c == 0:
+			return parseBreachCount(count)
+		case c < 0:
+			lo = next
+		default:
+			hi = start
+		}
+	}
+
+	return 0, nil
+}
+
+// lineAfter returns the first complete line starting at or after off, its
+// start offset and the offset of the following line. start is db.size when
+// no line starts at or after off.
+func (db *BreachDB) lineAfter(off int64) (line []byte, start, next int64, err error) {
+	buf := make([]byte, breachReadSize)
+
+	start = off
+	if off > 0 {
+		// Skip the remainder of the line containing off-1
+		n, err := db.file.ReadAt(buf, off-1)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, 0, 0, fmt.Errorf("reading breach file: %w", err)
+		}
+		i := bytes.IndexByte(buf[:n], '\n')
+		if i < 0 {
+			return nil, db.size, db.size, nil
+		}
+		start = off + int64(i)
+	}
+	if start >= db.size {
+		return nil, db.size, db.size, nil
+	}
+
+	n, err := db.file.ReadAt(buf, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, 0, fmt.Errorf("reading breach file: %w", err)
+	}
+	line = buf[:n]
+	next = start + int64(n)
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+		next = start + int64(i) + 1
+	} else if next < db.size {
+		return nil, 0, 0, fmt.Errorf("breach file line at offset %d too long", start)
+	}
+
+	return bytes.TrimRight(line, "\r"), start, next, nil
+}
+
+// parseBreachCount parses the count field of a corpus line. Entries without
+// a count are treated as seen once.
+func parseBreachCount(field []byte) (uint64, error) {
+	field = bytes.TrimSpace(field)
+	if len(field) == 0 {
+		return 1, nil
+	}
+
+	count, err := strconv.ParseUint(string(field), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid breach count %q: %w", field, err)
+	}
+	return count, nil
+}
+
+// generateScreened generates a string of the configured type, regenerating
+// candidates that appear in the configured breach corpus
+func (cg *CryptoGenerator) generateScreened(ctx context.Context, config *GeneratorConfig) (string, error) {
+	if config.BreachDB == nil {
+		return cg.generateByType(ctx, config)
+	}
+
+	for range maxBreachAttempts {
+		result, err := cg.generateByType(ctx, config)
+		if err != nil {
+			return "", err
+		}
+
+		count, err := config.BreachDB.Lookup(result)
+		if err != nil {
+			return "", fmt.Errorf("screening against breach corpus: %w", err)
+		}
+		if count == 0 {
+			return result, nil
+		}
+		cg.stats.breached.Add(1)
+	}
+
+	return "", fmt.Errorf("no unbreached string after %d attempts", maxBreachAttempts)
+}
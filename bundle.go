@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// maxBundleSecrets bounds the entries of a bundle spec
+const maxBundleSecrets = 256
+
+// bundleNamePattern matches names usable as environment variables
+var bundleNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// BundleFormat identifies how a bundle of secrets is written
+type BundleFormat uint8
+
+// Supported bundle formats
+const (
+	BundleEnv BundleFormat = iota
+	BundleJSON
+	BundleShell
+	BundleKubernetes // Secret manifest
+	BundleKustomize  // secretGenerator env file
+	BundleSwarm      // One file per secret
+)
+
+// String implements fmt.Stringer for BundleFormat
+func (f BundleFormat) String() string {
+	switch f {
+	case BundleEnv:
+		return "env"
+	case BundleJSON:
+		return "json"
+	case BundleShell:
+		return "shell"
+	case BundleKubernetes:
+		return "k8s"
+	case BundleKustomize:
+		return "kustomize"
+	case BundleSwarm:
+		return "swarm"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseBundleFormat parses a string into BundleFormat
+func ParseBundleFormat(s string) (BundleFormat, error) {
+	switch strings.ToLower(s) {
+	case "env", "dotenv":
+		return BundleEnv, nil
+	case "json":
+		return BundleJSON, nil
+	case "shell", "export":
+		return BundleShell, nil
+	case "k8s", "kubernetes":
+		return BundleKubernetes, nil
+	case "kustomize":
+		return BundleKustomize, nil
+	case "swarm", "docker":
+		return BundleSwarm, nil
+	default:
+		return 0, fmt.Errorf("invalid bundle format: %q", s)
+	}
+}
+
+// BundleEntry is one secret of a bundle spec. Keys match the command-line
+// flags of the same name, and omitted keys take the same defaults.
+type BundleEntry struct {
+	Type             string  `yaml:"type"`
+	Length           *int    `yaml:"length"` // Unset keeps the type's default
+	Charset          string  `yaml:"charset"`
+	ExcludeAmbiguous bool    `yaml:"exclude-ambiguous"`
+	MinUpper         int     `yaml:"min-upper"`
+	MinLower         int     `yaml:"min-lower"`
+	MinDigit         int     `yaml:"min-digit"`
+	MinSymbol        int     `yaml:"min-symbol"`
+	Forbid           string  `yaml:"forbid"`
+	MaxRepeat        int     `yaml:"max-repeat"`
+	Pattern          string  `yaml:"pattern"`
+	Bits             int     `yaml:"bits"`
+	Encoding         string  `yaml:"encoding"`
+	Prefix           string  `yaml:"prefix"`
+	Checksum         string  `yaml:"checksum"`
+	Words            int     `yaml:"words"`
+	Groups           int     `yaml:"groups"`
+	GroupSize        int     `yaml:"group-size"`
+	Separator        string  `yaml:"separator"`
+	Capitalize       bool    `yaml:"capitalize"`
+	AppendDigit      bool    `yaml:"append-digit"`
+	MinEntropy       float64 `yaml:"min-entropy"`
+}
+
+// defaultBundleEntry returns an entry holding the command-line defaults
+func defaultBundleEntry() BundleEntry {
+	return BundleEntry{
+		Type:      "hyphenated",
+		Charset:   alphanumericChars,
+		Bits:      defaultTokenBits,
+		Encoding:  "hex",
+		Checksum:  "crc32",
+		Words:     6,
+		Groups:    defaultGroups,
+		GroupSize: defaultGroupSize,
+		Separator: defaultSeparator,
+	}
+}
+
+// Config builds and validates the generator configuration of the entry
+func (e *BundleEntry) Config() (*GeneratorConfig, error) {
+	genType, err := ParseGeneratorType(e.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	charset, err := ParseCharset(e.Charset)
+	if err != nil {
+		return nil, err
+	}
+	if e.ExcludeAmbiguous {
+		charset = charset.Without(ambiguousChars)
+		if charset.Len() == 0 {
+			return nil, errors.New("charset is empty after removing ambiguous characters")
+		}
+	}
+
+	if err := validateLayout(genType, e.Groups, e.GroupSize, e.Separator); err != nil {
+		return nil, err
+	}
+
+	// Like --length, an omitted length leaves the hyphenated layout alone
+	length := defaultLength
+	if genType == GeneratorHyphenated {
+		length = 0
+	}
+	if e.Length != nil {
+		length = *e.Length
+	}
+
+	var pattern *Pattern
+	if genType == GeneratorPattern {
+		pattern, err = ParsePattern(e.Pattern, charset)
+		if err != nil {
+			return nil, fmt.Errorf("parsing pattern: %w", err)
+		}
+	}
+
+	encoding, err := ParseTokenEncoding(e.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := ParseChecksumAlgorithm(e.Checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &GeneratorConfig{
+		Type:    genType,
+		Length:  length,
+		Count:   1,
+		Charset: charset,
+		Policy: PasswordPolicy{
+			MinUpper:  e.MinUpper,
+			MinLower:  e.MinLower,
+			MinDigit:  e.MinDigit,
+			MinSymbol: e.MinSymbol,
+			Forbidden: e.Forbid,
+			MaxRepeat: e.MaxRepeat,
+		},
+		Pattern:      pattern,
+		Bits:         e.Bits,
+		Encoding:     encoding,
+		Prefix:       e.Prefix,
+		Checksum:     checksum,
+		Groups:       e.Groups,
+		GroupSize:    e.GroupSize,
+		Words:        e.Words,
+		Separator:    e.Separator,
+		Capitalize:   e.Capitalize,
+		AppendDigit:  e.AppendDigit,
+		MinEntropy:   e.MinEntropy,
+		Workers:      1,
+		MemoryPool:   true,
+		ConstantTime: true,
+	}
+	return config, config.Validate()
+}
+
+// BundleSecret is a named secret of a bundle
+type BundleSecret struct {
+	Name   string
+	Config *GeneratorConfig
+	Value  string
+}
+
+// ParseBundleSpec reads a YAML mapping of secret names to entries, keeping
+// the order of the file. Unknown keys are rejected, and every invalid entry
+// is reported, so that a bundle is only generated when all of it is valid.
+func ParseBundleSpec(r io.Reader) ([]BundleSecret, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("bundle spec defines no secrets")
+		}
+		return nil, fmt.Errorf("parsing bundle spec: %w", err)
+	}
+	mapping := doc.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("parsing bundle spec: line %d: expected a mapping of names to secrets", mapping.Line)
+	}
+
+	count := len(mapping.Content) / 2
+	if count == 0 {
+		return nil, errors.New("bundle spec defines no secrets")
+	}
+	if count > maxBundleSecrets {
+		return nil, fmt.Errorf("bundle spec defines %d secrets (max %d)", count, maxBundleSecrets)
+	}
+
+	secrets := make([]BundleSecret, 0, count)
+	seen := make(map[string]bool, count)
+	var errs []error
+	for i := 0; i < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		name := key.Value
+		switch {
+		case !bundleNamePattern.MatchString(name):
+			errs = append(errs, fmt.Errorf("line %d: invalid name %q (letters, digits and '_', not starting with a digit)", key.Line, name))
+			continue
+		case seen[name]:
+			errs = append(errs, fmt.Errorf("line %d: duplicate name %s", key.Line, name))
+			continue
+		}
+		seen[name] = true
+
+		entry := defaultBundleEntry()
+		if err := decodeStrict(value, &entry); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		config, err := entry.Config()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: line %d: %w", name, key.Line, err))
+			continue
+		}
+		secrets = append(secrets, BundleSecret{Name: name, Config: config})
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// decodeStrict decodes a mapping node into v, rejecting keys that match no
+// yaml tag of v's struct type. Fields missing from node keep their values.
+func decodeStrict(node *yaml.Node, v any) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping of settings", node.Line)
+	}
+
+	known := make(map[string]bool)
+	t := reflect.TypeOf(v).Elem()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		known[name] = true
+	}
+
+	var errs []error
+	for i := 0; i < len(node.Content); i += 2 {
+		if key := node.Content[i]; !known[key.Value] {
+			errs = append(errs, fmt.Errorf("line %d: unknown setting %q", key.Line, key.Value))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return node.Decode(v)
+}
+
+// GenerateBundle generates every secret of a bundle. Either all values are
+// set, or none are and the first failure is returned.
+func (cg *CryptoGenerator) GenerateBundle(ctx context.Context, secrets []BundleSecret) error {
+	for i := range secrets {
+		value, err := cg.Generate(ctx, secrets[i].Config)
+		if err != nil {
+			for j := range secrets[:i] {
+				secrets[j].Value = ""
+			}
+			return fmt.Errorf("generating %s: %w", secrets[i].Name, err)
+		}
+		secrets[i].Value = value
+	}
+	return nil
+}
+
+// WriteBundle writes generated secrets in format. For Docker Swarm, the
+// secrets go to files in opts.Dir and w receives the compose section
+// referencing them.
+func WriteBundle(w io.Writer, secrets []BundleSecret, format BundleFormat, opts BundleOptions) error {
+	if err := opts.Validate(format); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	defer func() { clear(buf.Bytes()) }()
+	switch format {
+	case BundleKubernetes:
+		if err := writeK8sSecret(&buf, secrets, opts); err != nil {
+			return err
+		}
+	case BundleKustomize:
+		if err := writeKustomizeEnv(&buf, secrets); err != nil {
+			return err
+		}
+	case BundleSwarm:
+		return writeSwarmSecrets(w, opts.Dir, secrets)
+	case BundleJSON:
+		buf.WriteString("{\n")
+		for i, s := range secrets {
+			name, _ := json.Marshal(s.Name)
+			value, _ := json.Marshal(s.Value)
+			fmt.Fprintf(&buf, "  %s: %s", name, value)
+			if i < len(secrets)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString("}\n")
+	case BundleShell:
+		for _, s := range secrets {
+			fmt.Fprintf(&buf, "export %s=%s\n", s.Name, shellQuote(s.Value))
+		}
+	default:
+		for _, s := range secrets {
+			fmt.Fprintf(&buf, "%s=%s\n", s.Name, dotenvQuote(s.Value))
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// shellQuote quotes s for POSIX shells. Single quotes disable every
+// expansion; an embedded single quote closes the quoting, is escaped and
+// reopens it.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// dotenvQuote leaves plain values bare and double-quotes the rest, escaping
+// the characters dotenv parsers interpret inside double quotes
+func dotenvQuote(s string) string {
+	if s != "" && strings.Trim(s, alphanumericChars+"_-.,:/@+=%") == "" {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`")
+	return `"` + r.Replace(s) + `"`
+}
+
+// writeFileAtomic writes data to path with mode 0600 through a temporary
+// file in the same directory, so readers never see a partial file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// newBundleCommand creates the bundle subcommand, which generates a set of
+// named secrets from a spec file
+func (app *Application) newBundleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "Generate a set of named secrets from a spec file",
+		Long: `Generate every secret named in a YAML spec file in one run, for example:
+
+  DB_PASSWORD:
+    type: compact
+    length: 32
+    charset: mysql-safe
+    min-digit: 2
+  SESSION_KEY:
+    type: token
+    bits: 256
+    encoding: base64url
+
+Entry keys match the flags of the main command and take the same defaults.
+Every entry is validated before anything is generated: if any entry is
+invalid or fails to generate, no secrets are written. Output keeps the order
+of the spec file.
+
+Besides .env, JSON and shell export lines, secrets can be wrapped for
+deployment: --format k8s writes a Kubernetes Secret manifest (--name,
+--namespace, --label), kustomize writes an env file for a secretGenerator,
+and swarm writes one file per secret into the --output directory and prints
+the matching compose secrets section.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         app.runBundle,
+	}
+
+	cmd.Flags().StringP("file", "f", "", "Bundle spec file, or - for stdin (required)")
+	cmd.Flags().StringP("format", "", "env", "Output format (env|json|shell|k8s|kustomize|swarm)")
+	cmd.Flags().StringP("output", "o", "", "Write to this file (mode 0600) instead of stdout; the secrets directory for swarm")
+	cmd.Flags().StringP("name", "", "", "Kubernetes Secret name (k8s format)")
+	cmd.Flags().StringP("namespace", "", "", "Kubernetes namespace (k8s format)")
+	cmd.Flags().StringToStringP("label", "", nil, "Kubernetes label as key=value, repeatable (k8s format)")
+
+	return cmd
+}
+
+// runBundle executes the bundle subcommand
+func (app *Application) runBundle(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	formatName, _ := flags.GetString("format")
+	format, err := ParseBundleFormat(formatName)
+	if err != nil {
+		return err
+	}
+
+	var opts BundleOptions
+	opts.Name, _ = flags.GetString("name")
+	opts.Namespace, _ = flags.GetString("namespace")
+	opts.Labels, _ = flags.GetStringToString("label")
+	path, _ := flags.GetString("output")
+	if format == BundleSwarm {
+		opts.Dir = path
+	}
+	if err := opts.Validate(format); err != nil {
+		return err
+	}
+
+	secrets, err := readBundleSpec(cmd)
+	if err != nil {
+		return err
+	}
+
+	if err := app.generator.GenerateBundle(cmd.Context(), secrets); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	defer func() { clear(buf.Bytes()) }()
+	if err := WriteBundle(&buf, secrets, format, opts); err != nil {
+		return err
+	}
+
+	if path != "" && format != BundleSwarm {
+		if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+			return fmt.Errorf("writing bundle: %w", err)
+		}
+		return nil
+	}
+	_, err = cmd.OutOrStdout().Write(buf.Bytes())
+	return err
+}
+
+// readBundleSpec parses the spec file named by --file
+func readBundleSpec(cmd *cobra.Command) ([]BundleSecret, error) {
+	path, _ := cmd.Flags().GetString("file")
+	switch path {
+	case "":
+		return nil, errors.New("--file is required")
+	case "-":
+		return ParseBundleSpec(cmd.InOrStdin())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening bundle spec: %w", err)
+	}
+	defer f.Close()
+	return ParseBundleSpec(f)
+}
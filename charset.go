@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ambiguousChars are characters easily confused with one another when read
+// or hand-typed
+const ambiguousChars = "0O1lI|"
+
+// charsetPresets maps preset names accepted by --charset to their characters
+var charsetPresets = map[string]string{
+	"alnum":      alphanumericChars,
+	"alpha":      lowerChars + upperChars,
+	"lower":      lowerChars,
+	"upper":      upperChars,
+	"digits":     digits,
+	"hex":        lowerHexChars,
+	"base32":     "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567",
+	"crockford":  "0123456789ABCDEFGHJKMNPQRSTVWXYZ",
+	"base58":     "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz",
+	"urlsafe":    upperChars + lowerChars + digits + "-_",
+	"symbols":    symbolChars,
+	"shell-safe": alphanumericChars + "%+,-./:=@_",
+	"mysql-safe": alphanumericChars + "!#$%&()*+,-.:<=>?@[]^_{|}~",
+}
+
+// CharsetPresets returns the sorted names of the built-in charset presets
+func CharsetPresets() []string {
+	names := make([]string, 0, len(charsetPresets))
+	for name := range charsetPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// CharsetName returns the name of the preset with exactly the characters of
+// cs, or "custom"
+func CharsetName(cs *CharacterSet) string {
+	if cs == nil {
+		return ""
+	}
+	for _, name := range CharsetPresets() {
+		if NewCharacterSet(charsetPresets[name]).String() == cs.String() {
+			return name
+		}
+	}
+	return "custom"
+}
+
+// ParseCharset parses a charset specification: either a preset name such as
+// "hex" or "base58", or a list of characters and ranges such as "a-z0-9_".
+// Ranges stay within lowercase letters, uppercase letters or digits; any
+// other '-' is literal, so symbol lists such as "!@#$%^&*()-_=+" keep their
+// meaning. '\' escapes the following character.
+func ParseCharset(spec string) (*CharacterSet, error) {
+	if preset, ok := charsetPresets[strings.ToLower(spec)]; ok {
+		return NewCharacterSet(preset), nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(spec); i++ {
+		lo := spec[i]
+		if lo == '\\' {
+			if i+1 >= len(spec) {
+				return nil, errors.New("charset ends with an unfinished escape")
+			}
+			i++
+			sb.WriteByte(spec[i])
+			continue
+		}
+
+		// A range needs a '-' followed by an upper bound of the same class
+		if i+2 < len(spec) && spec[i+1] == '-' && rangeClass(lo) != 0 && rangeClass(lo) == rangeClass(spec[i+2]) {
+			hi := spec[i+2]
+			i += 2
+			if hi < lo {
+				return nil, fmt.Errorf("invalid charset range %q-%q", lo, hi)
+			}
+			for c := int(lo); c <= int(hi); c++ {
+				sb.WriteByte(byte(c))
+			}
+			continue
+		}
+
+		sb.WriteByte(lo)
+	}
+
+	charset := NewCharacterSet(sb.String())
+	if charset.Len() == 0 {
+		return nil, errors.New("charset cannot be empty")
+	}
+	return charset, nil
+}
+
+// rangeClass returns the class a range bound belongs to: 'a' for lowercase
+// letters, 'A' for uppercase letters, '0' for digits, or 0 for anything else
+func rangeClass(c byte) byte {
+	switch {
+	case c >= 'a' && c <= 'z':
+		return 'a'
+	case c >= 'A' && c <= 'Z':
+		return 'A'
+	case c >= '0' && c <= '9':
+		return '0'
+	default:
+		return 0
+	}
+}
+
+// Without returns a copy of the character set with the given characters
+// removed
+func (cs *CharacterSet) Without(chars string) *CharacterSet {
+	var sb strings.Builder
+	for _, c := range cs.chars {
+		if strings.IndexByte(chars, c) < 0 {
+			sb.WriteByte(c)
+		}
+	}
+	return NewCharacterSet(sb.String())
+}
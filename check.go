@@ -0,0 +1,615 @@
+package main
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Strength estimation constants
+const (
+	minMatchLength    = 3
+	maxDictWordLength = 24
+	defaultCheckBits  = 60
+	minYear           = 1900
+	maxYear           = 2099
+	minYearSpace      = 20
+)
+
+// commonPasswords is a rank-ordered list of frequently used passwords
+//
+//go:embed wordlists/common_passwords.txt
+var commonPasswords string
+
+// strengthDictionaries lazily builds the rank tables used by the dictionary
+// matcher: common passwords by rank, and EFF words at uniform rank
+var strengthDictionaries = sync.OnceValue(func() map[string]float64 {
+	ranks := make(map[string]float64)
+	for _, w := range defaultWordList().words {
+		if len(w) >= minMatchLength {
+			ranks[w] = float64(defaultWordList().Len())
+		}
+	}
+	for i, w := range strings.Fields(commonPasswords) {
+		ranks[w] = float64(i + 1)
+	}
+	return ranks
+})
+
+// l33tTable maps common character substitutions to the letters they replace
+var l33tTable = map[byte]byte{
+	'4': 'a', '@': 'a', '3': 'e', '1': 'i', '!': 'i',
+	'0': 'o', '$': 's', '5': 's', '7': 't', '+': 't',
+}
+
+// keyboardRows describes the QWERTY layout, unshifted and shifted
+var keyboardRows = [][2]string{
+	{"`1234567890-=", "~!@#$%^&*()_+"},
+	{"qwertyuiop[]\\", "QWERTYUIOP{}|"},
+	{"asdfghjkl;'", "ASDFGHJKL:\""},
+	{"zxcvbnm,./", "ZXCVBNM<>?"},
+}
+
+// keyPosition is the row and column of a key on the keyboard
+type keyPosition struct {
+	row, col int
+}
+
+// keyboardPositions maps every printable key to its position
+var keyboardPositions = sync.OnceValue(func() map[byte]keyPosition {
+	positions := make(map[byte]keyPosition)
+	for r, row := range keyboardRows {
+		for c := range len(row[0]) {
+			positions[row[0][c]] = keyPosition{r, c}
+			positions[row[1][c]] = keyPosition{r, c}
+		}
+	}
+	return positions
+})
+
+// MatchKind identifies the kind of weakness a pattern match represents
+type MatchKind uint8
+
+// Pattern kinds detected by EstimateStrength
+const (
+	MatchDictionary MatchKind = iota + 1
+	MatchKeyboard
+	MatchRepeat
+	MatchSequence
+	MatchDate
+)
+
+// String implements fmt.Stringer for MatchKind
+func (k MatchKind) String() string {
+	switch k {
+	case MatchDictionary:
+		return "dictionary"
+	case MatchKeyboard:
+		return "keyboard"
+	case MatchRepeat:
+		return "repeat"
+	case MatchSequence:
+		return "sequence"
+	case MatchDate:
+		return "date"
+	default:
+		return "unknown"
+	}
+}
+
+// PatternMatch is a guessable substring password[Start:End]
+type PatternMatch struct {
+	Kind    MatchKind
+	Start   int
+	End     int
+	Guesses float64
+}
+
+// StrengthReport summarizes the estimated strength of a password
+type StrengthReport struct {
+	Length      int
+	CharsetBits float64        // Naive entropy from length and character classes
+	Bits        float64        // Estimated entropy after pattern analysis
+	Matches     []PatternMatch // Matches in the cheapest decomposition
+}
+
+// EstimateStrength estimates the number of guesses an attacker needs for
+// password, in the spirit of zxcvbn: it finds dictionary words, keyboard
+// walks, repeats, sequences and dates, then takes the cheapest way to
+// compose the password from those matches and brute-forced characters.
+func EstimateStrength(password string) *StrengthReport {
+	n := len(password)
+	cardinality := float64(passwordCardinality(password))
+	report := &StrengthReport{Length: n}
+	if n == 0 {
+		return report
+	}
+	report.CharsetBits = float64(n) * math.Log2(cardinality)
+
+	var matches []PatternMatch
+	matches = append(matches, dictionaryMatches(password)...)
+	matches = append(matches, keyboardMatches(password)...)
+	matches = append(matches, repeatMatches(password)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, dateMatches(password)...)
+
+	// cost[i] is the minimal log2 guesses for password[:i]
+	cost := make([]float64, n+1)
+	choice := make([]*PatternMatch, n+1)
+	for i := 1; i <= n; i++ {
+		cost[i] = cost[i-1] + math.Log2(cardinality)
+		choice[i] = nil
+		for j := range matches {
+			m := &matches[j]
+			if m.End != i {
+				continue
+			}
+			if c := cost[m.Start] + math.Log2(m.Guesses); c < cost[i] {
+				cost[i] = c
+				choice[i] = m
+			}
+		}
+	}
+
+	for i := n; i > 0; {
+		if m := choice[i]; m != nil {
+			report.Matches = append(report.Matches, *m)
+			i = m.Start
+		} else {
+			i--
+		}
+	}
+	slices.Reverse(report.Matches)
+
+	report.Bits = min(cost[n], report.CharsetBits)
+	return report
+}
+
+// passwordCardinality returns the size of the character space spanned by
+// the classes present in password
+func passwordCardinality(password string) int {
+	var seen [numCharClasses]bool
+	for i := 0; i < len(password); i++ {
+		seen[classOf(password[i])] = true
+	}
+
+	sizes := [numCharClasses]int{len(upperChars), len(lowerChars), len(digits), len(symbolChars)}
+	total := 0
+	for class, ok := range seen {
+		if ok {
+			total += sizes[class]
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return total
+}
+
+// dictionaryMatches finds dictionary words, including reversed and l33t
+// variants and capitalized forms
+func dictionaryMatches(password string) []PatternMatch {
+	ranks := strengthDictionaries()
+	var matches []PatternMatch
+
+	for i := range len(password) {
+		for j := i + minMatchLength; j <= min(len(password), i+maxDictWordLength); j++ {
+			token := password[i:j]
+			lower := strings.ToLower(token)
+			multiplier := uppercaseVariations(token)
+
+			if rank, ok := ranks[lower]; ok {
+				matches = append(matches, PatternMatch{MatchDictionary, i, j, rank * multiplier})
+			}
+
+			if reversed := reverseString(lower); reversed != lower {
+				if rank, ok := ranks[reversed]; ok {
+					matches = append(matches, PatternMatch{MatchDictionary, i, j, rank * multiplier * 2})
+				}
+			}
+
+			if unleeted, subs := unl33t(lower); subs > 0 {
+				if rank, ok := ranks[unleeted]; ok {
+					matches = append(matches, PatternMatch{MatchDictionary, i, j, rank * multiplier * math.Exp2(float64(subs))})
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// uppercaseVariations estimates the extra guesses needed for the
+// capitalization of token
+func uppercaseVariations(token string) float64 {
+	upper := 0
+	for i := 0; i < len(token); i++ {
+		if classOf(token[i]) == ClassUpper {
+			upper++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 1
+	case upper == len(token), upper == 1 && classOf(token[0]) == ClassUpper:
+		return 2
+	default:
+		return math.Exp2(float64(upper))
+	}
+}
+
+// unl33t reverses common character substitutions, returning the result and
+// the number of substitutions made
+func unl33t(token string) (string, int) {
+	b := []byte(token)
+	subs := 0
+	for i, c := range b {
+		if r, ok := l33tTable[c]; ok {
+			b[i] = r
+			subs++
+		}
+	}
+	return string(b), subs
+}
+
+// reverseString reverses an ASCII string
+func reverseString(s string) string {
+	b := []byte(s)
+	slices.Reverse(b)
+	return string(b)
+}
+
+// keyboardMatches finds walks of adjacent keys on a QWERTY keyboard
+func keyboardMatches(password string) []PatternMatch {
+	positions := keyboardPositions()
+	var matches []PatternMatch
+
+	i := 0
+	for i < len(password)-1 {
+		j, turns := i+1, 0
+		lastDir := keyPosition{}
+		for j < len(password) {
+			a, okA := positions[password[j-1]]
+			b, okB := positions[password[j]]
+			if !okA || !okB || !adjacentKeys(a, b) {
+				break
+			}
+			dir := keyPosition{b.row - a.row, b.col - a.col}
+			if dir != lastDir {
+				turns++
+				lastDir = dir
+			}
+			j++
+		}
+
+		if j-i >= minMatchLength {
+			matches = append(matches, PatternMatch{MatchKeyboard, i, j, keyboardGuesses(j-i, turns)})
+		}
+		if j-1 > i {
+			i = j - 1
+		} else {
+			i++
+		}
+	}
+
+	return matches
+}
+
+// adjacentKeys reports whether two keys touch on a staggered QWERTY layout
+func adjacentKeys(a, b keyPosition) bool {
+	switch b.row - a.row {
+	case 0:
+		return b.col-a.col == 1 || a.col-b.col == 1
+	case -1:
+		return b.col == a.col || b.col == a.col+1
+	case 1:
+		return b.col == a.col || b.col == a.col-1
+	default:
+		return false
+	}
+}
+
+// keyboardGuesses estimates guesses for a keyboard walk of the given length
+// and number of direction changes, following zxcvbn's spatial formula
+func keyboardGuesses(length, turns int) float64 {
+	const startingKeys, avgDegree = 47.0, 4.6
+	var guesses float64
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * startingKeys * math.Pow(avgDegree, float64(j))
+		}
+	}
+	return math.Max(guesses, 1)
+}
+
+// binomial returns n choose k
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	r := 1.0
+	for i := 1; i <= k; i++ {
+		r = r * float64(n-k+i) / float64(i)
+	}
+	return r
+}
+
+// repeatMatches finds runs of a repeated character or repeated substring
+func repeatMatches(password string) []PatternMatch {
+	var matches []PatternMatch
+	n := len(password)
+
+	for i := range n {
+		for unit := 1; unit <= (n-i)/2; unit++ {
+			j := i + unit
+			for j+unit <= n && password[j:j+unit] == password[i:i+unit] {
+				j += unit
+			}
+			if count := (j - i) / unit; count >= 2 && j-i >= minMatchLength {
+				base := math.Pow(float64(passwordCardinality(password[i:i+unit])), float64(unit))
+				matches = append(matches, PatternMatch{MatchRepeat, i, j, base * float64(count)})
+			}
+		}
+	}
+
+	return matches
+}
+
+// sequenceMatches finds runs of consecutive characters such as "abc",
+// "9876" or "XYZ"
+func sequenceMatches(password string) []PatternMatch {
+	var matches []PatternMatch
+	n := len(password)
+
+	i := 0
+	for i < n-1 {
+		delta := int(password[i+1]) - int(password[i])
+		j := i + 1
+		if delta == 1 || delta == -1 {
+			for j < n && int(password[j])-int(password[j-1]) == delta &&
+				classOf(password[j]) == classOf(password[i]) {
+				j++
+			}
+		}
+
+		if j-i >= minMatchLength {
+			var base float64
+			switch {
+			case strings.IndexByte("aAzZ019", password[i]) >= 0:
+				base = 4
+			case classOf(password[i]) == ClassDigit:
+				base = 10
+			default:
+				base = 26
+			}
+			if delta < 0 {
+				base *= 2
+			}
+			matches = append(matches, PatternMatch{MatchSequence, i, j, base * float64(j-i)})
+			i = j - 1
+			continue
+		}
+		i++
+	}
+
+	return matches
+}
+
+// dateMatches finds years and full dates in common digit layouts, with or
+// without separators
+func dateMatches(password string) []PatternMatch {
+	var matches []PatternMatch
+	n := len(password)
+
+	for i := range n {
+		// Four-digit years
+		if i+4 <= n {
+			if year, ok := parseDigits(password[i : i+4]); ok && year >= minYear && year <= maxYear {
+				matches = append(matches, PatternMatch{MatchDate, i, i + 4, yearSpace(year)})
+			}
+		}
+
+		// Eight-digit dates: yyyymmdd, ddmmyyyy, mmddyyyy
+		if i+8 <= n {
+			if year, ok := findDate(password[i:i+8], ""); ok {
+				matches = append(matches, PatternMatch{MatchDate, i, i + 8, 365 * yearSpace(year)})
+			}
+		}
+
+		// Ten-character dates with separators: yyyy-mm-dd, dd/mm/yyyy, ...
+		if i+10 <= n {
+			for _, sep := range []string{"-", "/", ".", "_", " "} {
+				if year, ok := findDate(password[i:i+10], sep); ok {
+					matches = append(matches, PatternMatch{MatchDate, i, i + 10, 4 * 365 * yearSpace(year)})
+					break
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// findDate checks whether s is a full date with the given separator in any
+// of the supported layouts, returning the year
+func findDate(s, sep string) (int, bool) {
+	if sep == "" {
+		if year, ok := checkDate(s[0:4], s[4:6], s[6:8]); ok {
+			return year, true
+		}
+		if year, ok := checkDate(s[4:8], s[2:4], s[0:2]); ok {
+			return year, true
+		}
+		return checkDate(s[4:8], s[0:2], s[2:4])
+	}
+
+	parts := strings.Split(s, sep)
+	if len(parts) != 3 {
+		return 0, false
+	}
+	switch {
+	case len(parts[0]) == 4 && len(parts[1]) == 2 && len(parts[2]) == 2:
+		return checkDate(parts[0], parts[1], parts[2])
+	case len(parts[0]) == 2 && len(parts[1]) == 2 && len(parts[2]) == 4:
+		if year, ok := checkDate(parts[2], parts[1], parts[0]); ok {
+			return year, true
+		}
+		return checkDate(parts[2], parts[0], parts[1])
+	default:
+		return 0, false
+	}
+}
+
+// checkDate validates year, month and day digit strings
+func checkDate(y, m, d string) (int, bool) {
+	year, okY := parseDigits(y)
+	month, okM := parseDigits(m)
+	day, okD := parseDigits(d)
+	if !okY || !okM || !okD {
+		return 0, false
+	}
+	if year < minYear || year > maxYear || month < 1 || month > 12 || day < 1 || day > 31 {
+		return 0, false
+	}
+	return year, true
+}
+
+// parseDigits parses an all-digit string
+func parseDigits(s string) (int, bool) {
+	v := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, false
+		}
+		v = v*10 + int(s[i]-'0')
+	}
+	return v, len(s) > 0
+}
+
+// yearSpace estimates guesses for a year close to the current year
+func yearSpace(year int) float64 {
+	diff := year - time.Now().Year()
+	if diff < 0 {
+		diff = -diff
+	}
+	return math.Max(float64(diff), minYearSpace)
+}
+
+// newCheckCommand creates the check subcommand, which estimates the strength
+// of passwords read from stdin
+func (app *Application) newCheckCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Estimate the strength of existing passwords",
+		Long: `Estimate the strength of passwords read from stdin, one per line.
+
+Passwords are never accepted as arguments. Each line is reported with its
+naive charset entropy and an estimate that accounts for dictionary words,
+keyboard walks, repeats, sequences and dates. With --breach-db, passwords
+are also looked up in a local Have I Been Pwned hash file and any breached
+password counts as weak. The command exits non-zero if any password is weak
+or falls below --min-entropy.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         app.runCheck,
+	}
+
+	cmd.Flags().Float64P("min-entropy", "", defaultCheckBits, "Minimum estimated entropy in bits")
+	cmd.Flags().StringP("breach-db", "", "", "Sorted HIBP hash file to screen passwords against")
+	cmd.Flags().StringP("breach-hash", "", "sha1", "Hash format of --breach-db (sha1|ntlm)")
+
+	return cmd
+}
+
+// runCheck executes the check subcommand
+func (app *Application) runCheck(cmd *cobra.Command, args []string) error {
+	threshold, err := cmd.Flags().GetFloat64("min-entropy")
+	if err != nil {
+		return err
+	}
+
+	var db *BreachDB
+	if path, _ := cmd.Flags().GetString("breach-db"); path != "" {
+		name, _ := cmd.Flags().GetString("breach-hash")
+		hash, err := ParseBreachHash(name)
+		if err != nil {
+			return err
+		}
+		db, err = OpenBreachDB(path, hash)
+		if err != nil {
+			return fmt.Errorf("opening breach corpus: %w", err)
+		}
+		defer db.Close()
+	}
+
+	weak, total, err := checkPasswords(cmd.InOrStdin(), cmd.OutOrStdout(), threshold, db)
+	if err != nil {
+		return err
+	}
+
+	if weak > 0 {
+		return fmt.Errorf("%d of %d passwords below %g bits", weak, total, threshold)
+	}
+	return nil
+}
+
+// checkPasswords reports the strength of each line in r, returning the
+// number of weak passwords and the number checked. db may be nil.
+func checkPasswords(r io.Reader, w io.Writer, threshold float64, db *BreachDB) (weak, total int, err error) {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		password := strings.TrimRight(scanner.Text(), "\r")
+		if password == "" {
+			continue
+		}
+		total++
+
+		report := EstimateStrength(password)
+
+		var breached uint64
+		if db != nil {
+			breached, err = db.Lookup(password)
+			if err != nil {
+				return weak, total, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+
+		status := "ok"
+		if report.Bits < threshold || breached > 0 {
+			status = "WEAK"
+			weak++
+		}
+
+		kinds := make([]string, 0, len(report.Matches))
+		for _, m := range report.Matches {
+			kinds = append(kinds, fmt.Sprintf("%s(%d)", m.Kind, m.End-m.Start))
+		}
+		patterns := "none"
+		if len(kinds) > 0 {
+			patterns = strings.Join(kinds, " ")
+		}
+
+		fmt.Fprintf(w, "line %d: %s %.1f bits (charset %.1f bits, length %d) patterns: %s",
+			line, status, report.Bits, report.CharsetBits, report.Length, patterns)
+		if db != nil {
+			fmt.Fprintf(w, " breached: %d", breached)
+		}
+		fmt.Fprintln(w)
+	}
+	if err := scanner.Err(); err != nil {
+		return weak, total, fmt.Errorf("reading passwords: %w", err)
+	}
+
+	return weak, total, nil
+}
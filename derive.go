@@ -0,0 +1,211 @@
+package main
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/term"
+)
+
+// Derivation parameters. Changing any of them changes every derived
+// password, so they are versioned together by derivationContext.
+const (
+	derivationContext  = "genpass/derive/v1"
+	derivationTime     = 3
+	derivationMemory   = 64 * 1024 // KiB
+	derivationThreads  = 4
+	derivationKeySize  = 32
+	defaultDerivedSize = 20
+)
+
+// DeriveMasterKey stretches a master passphrase with Argon2id, salted with
+// the login so that the same passphrase yields unrelated keys per identity.
+// This is the slow step; the result can be reused for many sites.
+func DeriveMasterKey(passphrase []byte, login string) []byte {
+	salt := appendLenPrefixed([]byte(derivationContext), login)
+	return argon2.IDKey(passphrase, salt, derivationTime, derivationMemory, derivationThreads, derivationKeySize)
+}
+
+// DeriveSitePassword deterministically derives the password for a site from
+// a master key. The site, counter, length and charset are bound with HKDF,
+// and the resulting key drives a ChaCha20 stream that is mapped onto the
+// charset by rejection sampling. Candidates violating the policy are
+// skipped, continuing the same stream.
+//
+// The mapping is deliberately independent of the random generators so that
+// derived passwords stay stable across releases.
+func DeriveSitePassword(masterKey []byte, site string, counter uint32, length int, charset *CharacterSet, policy PasswordPolicy) (string, error) {
+	if site == "" {
+		return "", errors.New("site cannot be empty")
+	}
+	if length <= 0 || length > maxStringLength {
+		return "", fmt.Errorf("invalid length: %d (must be 1-%d)", length, maxStringLength)
+	}
+	if charset == nil || charset.Len() == 0 {
+		return "", errors.New("charset cannot be empty")
+	}
+	if !policy.IsZero() {
+		if err := policy.Validate(length, charset); err != nil {
+			return "", err
+		}
+		charset = policy.Apply(charset)
+	}
+
+	info := appendLenPrefixed([]byte(derivationContext), site)
+	info = binary.BigEndian.AppendUint32(info, counter)
+	info = binary.BigEndian.AppendUint32(info, uint32(length))
+	info = appendLenPrefixed(info, charset.String())
+
+	siteKey, err := hkdf.Key(sha256.New, masterKey, nil, string(info), chacha20.KeySize)
+	if err != nil {
+		return "", fmt.Errorf("deriving site key: %w", err)
+	}
+	defer clear(siteKey)
+
+	stream, err := chacha20.NewUnauthenticatedCipher(siteKey, make([]byte, chacha20.NonceSize))
+	if err != nil {
+		return "", fmt.Errorf("deriving site key: %w", err)
+	}
+
+	n := uint32(charset.Len())
+	limit := ^uint32(0) - ^uint32(0)%n // Values at or above limit are biased
+	var word [4]byte
+	result := make([]byte, length)
+	defer clear(result)
+
+	attempts := 1
+	if !policy.IsZero() {
+		attempts = policy.attempts(length, charset)
+	}
+	for range attempts {
+		for i := range result {
+			for {
+				clear(word[:])
+				stream.XORKeyStream(word[:], word[:])
+				if v := binary.BigEndian.Uint32(word[:]); v < limit {
+					result[i] = charset.At(uint64(v % n))
+					break
+				}
+			}
+		}
+
+		if policy.Satisfied(string(result)) {
+			return string(result), nil
+		}
+	}
+
+	return "", fmt.Errorf("no string satisfying the policy after %d attempts - policy too restrictive", attempts)
+}
+
+// appendLenPrefixed appends s to b preceded by its 32-bit big-endian
+// length, so that adjacent fields cannot be confused
+func appendLenPrefixed(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+// newDeriveCommand creates the derive subcommand, which reproduces site
+// passwords from a master passphrase without storing anything
+func (app *Application) newDeriveCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "derive",
+		Short: "Derive reproducible site passwords from a master passphrase",
+		Long: `Derive a site password from a master passphrase, a site name, a login and
+a counter. The same inputs always produce the same password, so nothing needs
+to be stored; bump --counter to rotate a password.
+
+The master passphrase is read from the terminal without echo. It is
+stretched with Argon2id (salted with the login) and the site password is
+derived from it with HKDF-SHA256, then mapped onto the charset and policy.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         app.runDerive,
+	}
+
+	cmd.Flags().StringP("site", "", "", "Site name, e.g. example.com (required)")
+	cmd.Flags().StringP("login", "", "", "Login or user name for the site")
+	cmd.Flags().Uint32P("counter", "", 1, "Password version for the site")
+	cmd.Flags().IntP("length", "l", defaultDerivedSize, "Password length")
+	cmd.Flags().StringP("charset", "s", alphanumericChars, "Character set: preset ("+strings.Join(CharsetPresets(), "|")+") or characters and ranges like a-z0-9_")
+	cmd.Flags().IntP("min-upper", "", 0, "Minimum number of upper-case letters")
+	cmd.Flags().IntP("min-lower", "", 0, "Minimum number of lower-case letters")
+	cmd.Flags().IntP("min-digit", "", 0, "Minimum number of digits")
+	cmd.Flags().IntP("min-symbol", "", 0, "Minimum number of symbols")
+	cmd.Flags().StringP("forbid", "", "", "Characters that must not appear")
+	cmd.Flags().IntP("max-repeat", "", 0, "Maximum run of identical characters (0 = unlimited)")
+
+	return cmd
+}
+
+// runDerive executes the derive subcommand
+func (app *Application) runDerive(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	site, _ := flags.GetString("site")
+	if site == "" {
+		return errors.New("--site is required")
+	}
+	login, _ := flags.GetString("login")
+	counter, _ := flags.GetUint32("counter")
+	length, _ := flags.GetInt("length")
+
+	spec, _ := flags.GetString("charset")
+	charset, err := ParseCharset(spec)
+	if err != nil {
+		return err
+	}
+
+	var policy PasswordPolicy
+	policy.MinUpper, _ = flags.GetInt("min-upper")
+	policy.MinLower, _ = flags.GetInt("min-lower")
+	policy.MinDigit, _ = flags.GetInt("min-digit")
+	policy.MinSymbol, _ = flags.GetInt("min-symbol")
+	policy.Forbidden, _ = flags.GetString("forbid")
+	policy.MaxRepeat, _ = flags.GetInt("max-repeat")
+
+	passphrase, err := readMasterPassphrase()
+	if err != nil {
+		return err
+	}
+	defer clear(passphrase)
+
+	masterKey := DeriveMasterKey(passphrase, login)
+	defer clear(masterKey)
+
+	password, err := DeriveSitePassword(masterKey, site, counter, length, charset, policy)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), password)
+	return nil
+}
+
+// readMasterPassphrase prompts for the master passphrase on the terminal
+// without echo. It refuses to read from pipes so the passphrase never ends
+// up in shell history or scripts.
+func readMasterPassphrase() ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("master passphrase must be entered on a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, "Master passphrase: ")
+	passphrase, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("reading master passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("master passphrase cannot be empty")
+	}
+
+	return passphrase, nil
+}
@@ -0,0 +1,372 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SP 800-90A limits and defaults
+const (
+	drbgMaxRequest        = 1 << 16 // 2^19 bits per generate request
+	drbgMaxReseedInterval = 1 << 48
+	defaultReseedInterval = 1 << 20
+	ctrDRBGKeySize        = 32
+	ctrDRBGSeedSize       = ctrDRBGKeySize + aes.BlockSize
+	hmacDRBGEntropySize   = 32 // Security strength of 256 bits
+	hmacDRBGNonceSize     = 16 // Half the security strength
+	maxPersonalizationLen = 1 << 10
+)
+
+// DRBGMechanism identifies an SP 800-90A deterministic random bit generator
+type DRBGMechanism uint8
+
+// Supported DRBG mechanisms
+const (
+	DRBGNone DRBGMechanism = iota
+	DRBGHMACSHA256
+	DRBGCTRAES256
+)
+
+// String implements fmt.Stringer for DRBGMechanism
+func (m DRBGMechanism) String() string {
+	switch m {
+	case DRBGNone:
+		return "none"
+	case DRBGHMACSHA256:
+		return "hmac-sha256"
+	case DRBGCTRAES256:
+		return "ctr-aes256"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseDRBGMechanism parses a string into DRBGMechanism
+func ParseDRBGMechanism(s string) (DRBGMechanism, error) {
+	switch strings.ToLower(s) {
+	case "none", "":
+		return DRBGNone, nil
+	case "hmac-sha256", "hmac", "hmac_drbg":
+		return DRBGHMACSHA256, nil
+	case "ctr-aes256", "ctr", "ctr_drbg":
+		return DRBGCTRAES256, nil
+	default:
+		return 0, fmt.Errorf("invalid drbg mechanism: %q", s)
+	}
+}
+
+// DRBGConfig configures a DRBG entropy source
+type DRBGConfig struct {
+	Mechanism            DRBGMechanism
+	ReseedInterval       uint64 // Generate requests between reseeds
+	PredictionResistance bool   // Reseed before every request
+	Personalization      []byte
+}
+
+// Validate validates the DRBG configuration
+func (dc DRBGConfig) Validate() error {
+	var errs []error
+	if dc.Mechanism != DRBGHMACSHA256 && dc.Mechanism != DRBGCTRAES256 {
+		errs = append(errs, fmt.Errorf("invalid drbg mechanism: %v", dc.Mechanism))
+	}
+	if dc.ReseedInterval == 0 || dc.ReseedInterval > drbgMaxReseedInterval {
+		errs = append(errs, fmt.Errorf("invalid reseed interval: %d (must be 1-2^48)", dc.ReseedInterval))
+	}
+
+	limit := maxPersonalizationLen
+	if dc.Mechanism == DRBGCTRAES256 {
+		limit = ctrDRBGSeedSize // Without a derivation function
+	}
+	if len(dc.Personalization) > limit {
+		errs = append(errs, fmt.Errorf("personalization string too long: %d bytes (max %d)", len(dc.Personalization), limit))
+	}
+	return errors.Join(errs...)
+}
+
+// drbgMechanism is the internal state of a DRBG algorithm
+type drbgMechanism interface {
+	instantiate(entropy, nonce, personalization []byte)
+	reseed(entropy, additional []byte)
+	generate(out, additional []byte)
+	entropySize() int
+	nonceSize() int
+}
+
+// hmacDRBG implements HMAC_DRBG with SHA-256 (SP 800-90A 10.1.2)
+type hmacDRBG struct {
+	k, v [sha256.Size]byte
+}
+
+// update is HMAC_DRBG_Update over the concatenation of data. The second
+// round only runs when some data is provided.
+func (d *hmacDRBG) update(data ...[]byte) {
+	d.round(0x00, data)
+	for _, p := range data {
+		if len(p) > 0 {
+			d.round(0x01, data)
+			return
+		}
+	}
+}
+
+// round computes K = HMAC(K, V || b || data) and V = HMAC(K, V)
+func (d *hmacDRBG) round(b byte, data [][]byte) {
+	mac := hmac.New(sha256.New, d.k[:])
+	mac.Write(d.v[:])
+	mac.Write([]byte{b})
+	for _, p := range data {
+		mac.Write(p)
+	}
+	mac.Sum(d.k[:0])
+
+	mac = hmac.New(sha256.New, d.k[:])
+	mac.Write(d.v[:])
+	mac.Sum(d.v[:0])
+}
+
+func (d *hmacDRBG) instantiate(entropy, nonce, personalization []byte) {
+	for i := range d.k {
+		d.k[i], d.v[i] = 0x00, 0x01
+	}
+	d.update(entropy, nonce, personalization)
+}
+
+func (d *hmacDRBG) reseed(entropy, additional []byte) {
+	d.update(entropy, additional)
+}
+
+func (d *hmacDRBG) generate(out, additional []byte) {
+	if len(additional) > 0 {
+		d.update(additional)
+	}
+	for off := 0; off < len(out); off += sha256.Size {
+		mac := hmac.New(sha256.New, d.k[:])
+		mac.Write(d.v[:])
+		mac.Sum(d.v[:0])
+		copy(out[off:], d.v[:])
+	}
+	d.update(additional)
+}
+
+func (d *hmacDRBG) entropySize() int { return hmacDRBGEntropySize }
+func (d *hmacDRBG) nonceSize() int   { return hmacDRBGNonceSize }
+
+// ctrDRBG implements CTR_DRBG with AES-256 and no derivation function
+// (SP 800-90A 10.2.1)
+type ctrDRBG struct {
+	block cipher.Block
+	v     [aes.BlockSize]byte
+}
+
+// update is CTR_DRBG_Update; data is exactly seedlen bytes
+func (d *ctrDRBG) update(data *[ctrDRBGSeedSize]byte) {
+	var temp [ctrDRBGSeedSize]byte
+	for off := 0; off < len(temp); off += aes.BlockSize {
+		incrementBytes(d.v[:])
+		d.block.Encrypt(temp[off:], d.v[:])
+	}
+	for i := range temp {
+		temp[i] ^= data[i]
+	}
+
+	d.block, _ = aes.NewCipher(temp[:ctrDRBGKeySize]) // Key size is constant
+	copy(d.v[:], temp[ctrDRBGKeySize:])
+	clear(temp[:])
+}
+
+// seedMaterial XORs a and b into a seedlen block, zero-padding either
+func seedMaterial(a, b []byte) *[ctrDRBGSeedSize]byte {
+	var seed [ctrDRBGSeedSize]byte
+	copy(seed[:], a)
+	for i := range min(len(b), len(seed)) {
+		seed[i] ^= b[i]
+	}
+	return &seed
+}
+
+func (d *ctrDRBG) instantiate(entropy, nonce, personalization []byte) {
+	d.block, _ = aes.NewCipher(make([]byte, ctrDRBGKeySize))
+	clear(d.v[:])
+	d.update(seedMaterial(entropy, personalization))
+}
+
+func (d *ctrDRBG) reseed(entropy, additional []byte) {
+	d.update(seedMaterial(entropy, additional))
+}
+
+func (d *ctrDRBG) generate(out, additional []byte) {
+	add := seedMaterial(additional, nil)
+	if len(additional) > 0 {
+		d.update(add)
+	}
+
+	var block [aes.BlockSize]byte
+	for off := 0; off < len(out); off += aes.BlockSize {
+		incrementBytes(d.v[:])
+		d.block.Encrypt(block[:], d.v[:])
+		copy(out[off:], block[:])
+	}
+	clear(block[:])
+	d.update(add)
+}
+
+func (d *ctrDRBG) entropySize() int { return ctrDRBGSeedSize }
+func (d *ctrDRBG) nonceSize() int   { return 0 }
+
+// drbgSource is an entropy source expanding seed material from another
+// source with a DRBG
+type drbgSource struct {
+	seed   EntropySource
+	config DRBGConfig
+
+	mu       sync.Mutex
+	mech     drbgMechanism // nil until instantiated
+	requests uint64        // Generate requests since the last (re)seed
+	failure  error
+	stats    struct {
+		generated atomic.Uint64
+		errors    atomic.Uint64
+		reseeds   atomic.Uint64
+	}
+}
+
+// NewDRBGSource creates an SP 800-90A DRBG seeded from seed. The DRBG is
+// instantiated on first use and reseeded from seed after ReseedInterval
+// requests, or before every request with prediction resistance. Requests
+// larger than 2^19 bits are split. Closing the returned source closes seed
+// if it implements io.Closer.
+func NewDRBGSource(seed EntropySource, config DRBGConfig) (EntropySource, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config.Personalization = append([]byte(nil), config.Personalization...)
+	return &drbgSource{seed: seed, config: config}, nil
+}
+
+// String implements fmt.Stringer
+func (ds *drbgSource) String() string {
+	return fmt.Sprintf("%s-drbg(%s)", ds.config.Mechanism, ds.seed)
+}
+
+// Deterministic reports whether the seed source is deterministic, which
+// makes the whole DRBG output reproducible
+func (ds *drbgSource) Deterministic() bool {
+	return ds.seed.Deterministic()
+}
+
+// newMechanism returns fresh state for the configured mechanism
+func (ds *drbgSource) newMechanism() drbgMechanism {
+	if ds.config.Mechanism == DRBGCTRAES256 {
+		return &ctrDRBG{}
+	}
+	return &hmacDRBG{}
+}
+
+// GenerateBytes generates n bytes from the DRBG
+func (ds *drbgSource) GenerateBytes(n int) ([]byte, error) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.failure != nil {
+		return nil, fmt.Errorf("entropy source is unhealthy: %w", ds.failure)
+	}
+
+	buf := make([]byte, n)
+	for off := 0; off < n; off += drbgMaxRequest {
+		if err := ds.ensureSeeded(); err != nil {
+			ds.failure = err
+			ds.stats.errors.Add(1)
+			clear(buf)
+			return nil, err
+		}
+		ds.mech.generate(buf[off:min(off+drbgMaxRequest, n)], nil)
+		ds.requests++
+	}
+
+	ds.stats.generated.Add(uint64(n))
+	return buf, nil
+}
+
+// ensureSeeded instantiates or reseeds the DRBG when required
+func (ds *drbgSource) ensureSeeded() error {
+	if ds.mech != nil && !ds.config.PredictionResistance && ds.requests < ds.config.ReseedInterval {
+		return nil
+	}
+
+	mech := ds.mech
+	if mech == nil {
+		mech = ds.newMechanism()
+	}
+
+	size := mech.entropySize()
+	if ds.mech == nil {
+		size += mech.nonceSize()
+	}
+	input, err := ds.seed.GenerateBytes(size)
+	if err != nil {
+		return fmt.Errorf("seeding drbg: %w", err)
+	}
+	defer clear(input)
+
+	if ds.mech == nil {
+		mech.instantiate(input[:mech.entropySize()], input[mech.entropySize():], ds.config.Personalization)
+		ds.mech = mech
+	} else {
+		mech.reseed(input, nil)
+		ds.stats.reseeds.Add(1)
+	}
+	ds.requests = 0
+	return nil
+}
+
+// GenerateUint64 generates a random uint64 from the DRBG
+func (ds *drbgSource) GenerateUint64() (uint64, error) {
+	return uint64FromSource(ds)
+}
+
+// Health returns the DRBG failure or the health of its seed source
+func (ds *drbgSource) Health() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if ds.failure != nil {
+		return ds.failure
+	}
+	return ds.seed.Health()
+}
+
+// Reset clears a failure and forces the DRBG to be instantiated afresh
+func (ds *drbgSource) Reset() error {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	ds.failure = nil
+	ds.mech = nil
+	return ds.seed.Reset()
+}
+
+// Stats returns the bytes generated and the number of errors
+func (ds *drbgSource) Stats() (generated, errors uint64) {
+	return ds.stats.generated.Load(), ds.stats.errors.Load()
+}
+
+// Reseeds returns the number of reseeds since instantiation
+func (ds *drbgSource) Reseeds() uint64 {
+	return ds.stats.reseeds.Load()
+}
+
+// Close closes the seed source if it implements io.Closer
+func (ds *drbgSource) Close() error {
+	if c, ok := ds.seed.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"math"
+	"sync"
+)
+
+// maxPolicyStates bounds the state space of the policy acceptance model.
+// Larger policies fall back to a lower bound (see acceptanceBound).
+const maxPolicyStates = 1 << 20
+
+// acceptanceKey identifies a memoized policy acceptance probability
+type acceptanceKey struct {
+	policy  PasswordPolicy
+	length  int
+	charset string
+}
+
+// acceptanceCache memoizes policy acceptance probabilities, since Validate
+// runs for every generated string
+var acceptanceCache sync.Map // acceptanceKey -> float64
+
+// Entropy returns the entropy in bits of a single string produced by the
+// configuration. Policy constraints are accounted for exactly: the result is
+// log2 of the number of strings the generator can emit, all of which are
+// equally likely. Policies too large to model report a lower bound.
+func (gc *GeneratorConfig) Entropy() float64 {
+	switch gc.Type {
+	case GeneratorPassphrase:
+		wordlist := gc.Wordlist
+		if wordlist == nil {
+			wordlist = defaultWordList()
+		}
+		bits := float64(gc.Words) * math.Log2(float64(wordlist.Len()))
+		if gc.AppendDigit {
+			bits += math.Log2(float64(len(digits)))
+		}
+		return bits
+	case GeneratorPattern:
+		if gc.Pattern == nil {
+			return 0
+		}
+		return gc.Pattern.Entropy()
+	case GeneratorPronounceable:
+		return pronounceableEntropy(gc.Length)
+	case GeneratorToken, GeneratorMnemonic:
+		return float64(gc.Bits)
+	case GeneratorUUIDv4:
+		return 122
+	case GeneratorUUIDv7:
+		return 74
+	case GeneratorULID:
+		return ulidRandomBytes * 8
+	case GeneratorCompact:
+		return charsetEntropy(gc.Length, gc.Charset, gc.Policy)
+	case GeneratorAPIKey:
+		return charsetEntropy(gc.Length, gc.Charset, PasswordPolicy{})
+	default:
+		groups, size, _ := gc.hyphenatedLayout()
+		return charsetEntropy(groups*size, gc.Charset, PasswordPolicy{})
+	}
+}
+
+// charsetEntropy returns the entropy of uniformly chosen strings of the given
+// length over charset that satisfy policy
+func charsetEntropy(length int, charset *CharacterSet, policy PasswordPolicy) float64 {
+	charset = policy.Apply(charset)
+	if charset == nil || charset.Len() == 0 || length <= 0 {
+		return 0
+	}
+
+	bits := float64(length) * math.Log2(float64(charset.Len()))
+	if policy.IsZero() {
+		return bits
+	}
+
+	p := policy.cachedAcceptance(length, charset)
+	if p <= 0 {
+		return 0
+	}
+	return bits + math.Log2(p)
+}
+
+// cachedAcceptance returns the memoized acceptance probability of the policy
+// over charset, with forbidden characters already removed, or its lower
+// bound when the policy is too large to evaluate
+func (p PasswordPolicy) cachedAcceptance(length int, charset *CharacterSet) float64 {
+	key := acceptanceKey{policy: p, length: length, charset: charset.String()}
+	cached, found := acceptanceCache.Load(key)
+	if !found {
+		prob, ok := p.acceptance(length, charset)
+		if !ok {
+			prob = p.acceptanceBound(length, charset)
+		}
+		cached, _ = acceptanceCache.LoadOrStore(key, prob)
+	}
+	return cached.(float64)
+}
+
+// acceptance returns the probability that a uniformly random string of the
+// given length over charset satisfies the policy's class minimums and repeat
+// limit. ok is false when the state space is too large to evaluate.
+func (p PasswordPolicy) acceptance(length int, charset *CharacterSet) (prob float64, ok bool) {
+	var sizes [numCharClasses]int
+	for _, c := range charset.chars {
+		sizes[classOf(c)]++
+	}
+	n := float64(charset.Len())
+	mins := p.minimums()
+
+	// State: per-class counts capped at their minimums, plus the class and
+	// run length of the last character when a repeat limit applies
+	var radix [numCharClasses]int
+	states := 1
+	for class, m := range mins {
+		if m < 0 {
+			m, mins[class] = 0, 0
+		}
+		radix[class] = m + 1
+		states *= radix[class]
+		if states > maxPolicyStates {
+			return 0, false
+		}
+	}
+	runs := 1
+	if p.MaxRepeat > 0 {
+		runs = p.MaxRepeat
+	}
+	tail := int(numCharClasses) * runs
+	if states*tail > maxPolicyStates {
+		return 0, false
+	}
+
+	counts := func(idx int) (c [numCharClasses]int) {
+		for class := range c {
+			c[class] = idx % radix[class]
+			idx /= radix[class]
+		}
+		return c
+	}
+	index := func(c [numCharClasses]int) (idx int) {
+		for class := int(numCharClasses) - 1; class >= 0; class-- {
+			idx = idx*radix[class] + min(c[class], radix[class]-1)
+		}
+		return idx
+	}
+
+	cur := make([]float64, states*tail)
+	next := make([]float64, states*tail)
+
+	// First character
+	for class, size := range sizes {
+		if size == 0 {
+			continue
+		}
+		var c [numCharClasses]int
+		c[class] = 1
+		cur[index(c)*tail+class*runs] += float64(size) / n
+	}
+
+	for pos := 1; pos < length; pos++ {
+		clear(next)
+		for s, v := range cur {
+			if v == 0 {
+				continue
+			}
+			base, rest := s/tail, s%tail
+			last, run := rest/runs, rest%runs+1
+			c := counts(base)
+
+			for class, size := range sizes {
+				if size == 0 {
+					continue
+				}
+				nc := c
+				nc[class]++
+				ni := index(nc) * tail
+
+				if p.MaxRepeat == 0 {
+					next[ni] += v * float64(size) / n
+					continue
+				}
+
+				// A different character resets the run
+				different := size
+				if class == last {
+					different--
+				}
+				if different > 0 {
+					next[ni+class*runs] += v * float64(different) / n
+				}
+
+				// Repeating the previous character extends the run
+				if class == last && run < p.MaxRepeat {
+					next[ni+class*runs+run] += v / n
+				}
+			}
+		}
+		cur, next = next, cur
+	}
+
+	base := index(mins) * tail
+	for i := range tail {
+		prob += cur[base+i]
+	}
+	return prob, true
+}
+
+// acceptanceBound returns a lower bound on the acceptance probability of a
+// policy too large to evaluate exactly, so the reported entropy never
+// overstates it. The class minimums are evaluated without the repeat limit,
+// or, when even those are too large, bounded by the chance that the first
+// characters cover them in a fixed order. The chance of any run longer than
+// the repeat limit is then subtracted.
+func (p PasswordPolicy) acceptanceBound(length int, charset *CharacterSet) float64 {
+	minimums := p
+	minimums.MaxRepeat = 0
+	prob, ok := minimums.acceptance(length, charset)
+	if !ok {
+		var sizes [numCharClasses]int
+		for _, c := range charset.chars {
+			sizes[classOf(c)]++
+		}
+		n := float64(charset.Len())
+		prob = 1
+		required := 0
+		for class, m := range p.minimums() {
+			if m <= 0 {
+				continue
+			}
+			required += m
+			prob *= math.Pow(float64(sizes[class])/n, float64(m))
+		}
+		if required > length {
+			return 0
+		}
+	}
+
+	// Each of the length-MaxRepeat windows one character past the limit is
+	// a single repeated character with probability n^-MaxRepeat
+	if r := p.MaxRepeat; r > 0 && length > r {
+		prob -= float64(length-r) * math.Pow(float64(charset.Len()), -float64(r))
+	}
+	if prob < 0 {
+		return 0
+	}
+	return prob
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxErrorRetries bounds retry=N so a dead source cannot stall a run
+const maxErrorRetries = 100
+
+// ErrorAction determines what happens when a string cannot be generated
+type ErrorAction uint8
+
+// Supported error actions
+const (
+	ErrorFail  ErrorAction = iota // Stop at the first failure
+	ErrorSkip                     // Leave the string out and carry on
+	ErrorRetry                    // Retry a failure, then stop
+)
+
+// String implements fmt.Stringer for ErrorAction
+func (a ErrorAction) String() string {
+	switch a {
+	case ErrorFail:
+		return "fail"
+	case ErrorSkip:
+		return "skip"
+	case ErrorRetry:
+		return "retry"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrorPolicy is the error handling of batch and stream generation. The zero
+// value fails at the first error.
+type ErrorPolicy struct {
+	Action  ErrorAction
+	Retries int // Additional attempts per string with ErrorRetry
+}
+
+// String implements fmt.Stringer for ErrorPolicy
+func (p ErrorPolicy) String() string {
+	if p.Action == ErrorRetry {
+		return fmt.Sprintf("retry=%d", p.Retries)
+	}
+	return p.Action.String()
+}
+
+// skippable reports whether a failure may be left out under the policy.
+// Cancellation always stops generation.
+func (p ErrorPolicy) skippable(ctx context.Context) bool {
+	return p.Action == ErrorSkip && ctx.Err() == nil
+}
+
+// ParseErrorPolicy parses "fail", "skip" or "retry=N" into ErrorPolicy
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	action, arg, hasArg := strings.Cut(strings.ToLower(s), "=")
+	switch {
+	case action == "fail" && !hasArg:
+		return ErrorPolicy{Action: ErrorFail}, nil
+	case action == "skip" && !hasArg:
+		return ErrorPolicy{Action: ErrorSkip}, nil
+	case action == "retry":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 || n > maxErrorRetries {
+			return ErrorPolicy{}, fmt.Errorf("invalid retry count: %q (must be 1-%d)", arg, maxErrorRetries)
+		}
+		return ErrorPolicy{Action: ErrorRetry, Retries: n}, nil
+	default:
+		return ErrorPolicy{}, fmt.Errorf("invalid error policy: %q", s)
+	}
+}
+
+// ShortCountError reports that fewer strings were produced than requested.
+// Err is the last generation failure.
+type ShortCountError struct {
+	Requested int
+	Produced  int
+	Skipped   int
+	Err       error
+}
+
+// Error implements the error interface
+func (e *ShortCountError) Error() string {
+	msg := fmt.Sprintf("produced %d of %d strings", e.Produced, e.Requested)
+	if e.Skipped > 0 {
+		msg += fmt.Sprintf(" (%d skipped)", e.Skipped)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying generation failure
+func (e *ShortCountError) Unwrap() error {
+	return e.Err
+}
+
+// generateWithRetries generates one string, retrying failures as often as
+// the error policy allows. Cancellation is never retried.
+func (cg *CryptoGenerator) generateWithRetries(ctx context.Context, config *GeneratorConfig) (string, error) {
+	result, err := cg.Generate(ctx, config)
+	if config.OnError.Action != ErrorRetry {
+		return result, err
+	}
+
+	for range config.OnError.Retries {
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+		cg.stats.retries.Add(1)
+		result, err = cg.Generate(ctx, config)
+	}
+	return result, err
+}
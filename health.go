@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SP 800-90B health test parameters
+const (
+	healthAlphaExp       = 40   // False positive probability 2^-40 per sample
+	defaultHealthEntropy = 8.0  // Claimed min-entropy per byte of conditioned sources
+	defaultAPTWindow     = 512  // Window size for non-binary samples (4.4.2)
+	startupSamples       = 1024 // Samples tested before first use (4.3)
+	defaultMaxRecoveries = 3
+	maxHealthEntropy     = 8.0 // A byte cannot carry more
+)
+
+// Health test failures
+var (
+	ErrRepetitionCount    = errors.New("repetition count test failed")
+	ErrAdaptiveProportion = errors.New("adaptive proportion test failed")
+)
+
+// RecoveryPolicy determines what happens after a health test failure
+type RecoveryPolicy uint8
+
+// Supported recovery policies
+const (
+	RecoveryNone   RecoveryPolicy = iota // Stay failed until Reset
+	RecoveryRetest                       // Rerun the startup test on the next read
+)
+
+// String implements fmt.Stringer for RecoveryPolicy
+func (r RecoveryPolicy) String() string {
+	switch r {
+	case RecoveryNone:
+		return "none"
+	case RecoveryRetest:
+		return "retest"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseRecoveryPolicy parses a string into RecoveryPolicy
+func ParseRecoveryPolicy(s string) (RecoveryPolicy, error) {
+	switch strings.ToLower(s) {
+	case "none":
+		return RecoveryNone, nil
+	case "retest":
+		return RecoveryRetest, nil
+	default:
+		return 0, fmt.Errorf("invalid recovery policy: %q", s)
+	}
+}
+
+// HealthConfig configures the continuous health tests of an entropy source
+type HealthConfig struct {
+	RCTCutoff     int // Identical consecutive bytes that fail the Repetition Count Test
+	APTCutoff     int // Occurrences of the first byte of a window that fail the Adaptive Proportion Test
+	APTWindow     int // Adaptive Proportion Test window in bytes
+	Recovery      RecoveryPolicy
+	MaxRecoveries int // Automatic recoveries allowed before a failure latches
+}
+
+// DefaultHealthConfig returns the health test configuration for a source
+// claimed to deliver full entropy
+func DefaultHealthConfig() HealthConfig {
+	rct, apt := HealthCutoffs(defaultHealthEntropy, defaultAPTWindow)
+	return HealthConfig{
+		RCTCutoff:     rct,
+		APTCutoff:     apt,
+		APTWindow:     defaultAPTWindow,
+		Recovery:      RecoveryRetest,
+		MaxRecoveries: defaultMaxRecoveries,
+	}
+}
+
+// Validate validates the health test configuration
+func (hc HealthConfig) Validate() error {
+	var errs []error
+	if hc.RCTCutoff < 2 {
+		errs = append(errs, fmt.Errorf("invalid repetition count cutoff: %d", hc.RCTCutoff))
+	}
+	if hc.APTWindow < 2 {
+		errs = append(errs, fmt.Errorf("invalid adaptive proportion window: %d", hc.APTWindow))
+	}
+	if hc.APTCutoff < 2 || hc.APTCutoff > hc.APTWindow {
+		errs = append(errs, fmt.Errorf("invalid adaptive proportion cutoff: %d (must be 2-%d)", hc.APTCutoff, hc.APTWindow))
+	}
+	if hc.MaxRecoveries < 0 {
+		errs = append(errs, fmt.Errorf("invalid recovery limit: %d", hc.MaxRecoveries))
+	}
+	return errors.Join(errs...)
+}
+
+// HealthCutoffs returns the SP 800-90B Repetition Count and Adaptive
+// Proportion Test cutoffs for a source claiming h bits of min-entropy per
+// byte, at a false positive probability of 2^-40 per sample
+func HealthCutoffs(h float64, window int) (rct, apt int) {
+	h = math.Min(math.Max(h, 0.01), maxHealthEntropy)
+
+	// RCT (4.4.1): C = 1 + ceil(-log2(alpha) / H)
+	rct = 1 + int(math.Ceil(healthAlphaExp/h))
+
+	// APT (4.4.2): C = 1 + CRITBINOM(W, 2^-H, 1-alpha), found by summing the
+	// binomial upper tail in log space until it exceeds alpha
+	p := math.Exp2(-h)
+	logAlpha := -healthAlphaExp * math.Ln2
+	lw, _ := math.Lgamma(float64(window) + 1)
+	tail := math.Inf(-1)
+	k := window
+	for ; k > 0; k-- {
+		lk, _ := math.Lgamma(float64(k) + 1)
+		lnk, _ := math.Lgamma(float64(window-k) + 1)
+		logPMF := lw - lk - lnk + float64(k)*math.Log(p) + float64(window-k)*math.Log1p(-p)
+		next := logAddExp(tail, logPMF)
+		if next > logAlpha {
+			break
+		}
+		tail = next
+	}
+	return rct, min(1+k, window)
+}
+
+// logAddExp returns log(exp(a) + exp(b))
+func logAddExp(a, b float64) float64 {
+	if math.IsInf(a, -1) {
+		return b
+	}
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log1p(math.Exp(b-a))
+}
+
+// healthTester runs the continuous Repetition Count and Adaptive Proportion
+// Tests over a byte stream
+type healthTester struct {
+	config HealthConfig
+
+	last byte // RCT state
+	run  int
+
+	aptValue byte // APT state
+	aptCount int
+	aptIndex int
+}
+
+// feed tests each byte of b in order, returning the first failure
+func (ht *healthTester) feed(b []byte) error {
+	for _, v := range b {
+		if ht.run > 0 && v == ht.last {
+			ht.run++
+			if ht.run >= ht.config.RCTCutoff {
+				return fmt.Errorf("%w: byte %#02x repeated %d times (cutoff %d)", ErrRepetitionCount, v, ht.run, ht.config.RCTCutoff)
+			}
+		} else {
+			ht.last, ht.run = v, 1
+		}
+
+		if ht.aptIndex == 0 {
+			ht.aptValue, ht.aptCount = v, 1
+		} else if v == ht.aptValue {
+			ht.aptCount++
+			if ht.aptCount >= ht.config.APTCutoff {
+				return fmt.Errorf("%w: byte %#02x seen %d times in a window of %d (cutoff %d)", ErrAdaptiveProportion, v, ht.aptCount, ht.config.APTWindow, ht.config.APTCutoff)
+			}
+		}
+		ht.aptIndex = (ht.aptIndex + 1) % ht.config.APTWindow
+	}
+	return nil
+}
+
+// reset clears the test state
+func (ht *healthTester) reset() {
+	*ht = healthTester{config: ht.config}
+}
+
+// healthTestedSource applies SP 800-90B continuous health tests to the raw
+// output of another source
+type healthTestedSource struct {
+	source EntropySource
+	config HealthConfig
+
+	mu         sync.Mutex
+	tester     healthTester
+	started    bool  // Startup self-test passed
+	failure    error // Latched health test or read failure
+	recoveries int
+	stats      struct {
+		generated atomic.Uint64
+		failures  atomic.Uint64
+	}
+}
+
+// NewHealthTestedSource wraps source with a startup self-test and continuous
+// Repetition Count and Adaptive Proportion Tests. The startup test runs over
+// the first 1024 bytes before any output is released. After a failure,
+// reads fail with the reason until the recovery policy or Reset clears it.
+func NewHealthTestedSource(source EntropySource, config HealthConfig) (EntropySource, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	hs := &healthTestedSource{source: source, config: config}
+	hs.tester.config = config
+	return hs, nil
+}
+
+// String implements fmt.Stringer
+func (hs *healthTestedSource) String() string {
+	return hs.source.String()
+}
+
+// Deterministic reports whether the tested source is deterministic
+func (hs *healthTestedSource) Deterministic() bool {
+	return hs.source.Deterministic()
+}
+
+// GenerateBytes generates n health-tested random bytes
+func (hs *healthTestedSource) GenerateBytes(n int) ([]byte, error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.failure != nil {
+		if hs.config.Recovery != RecoveryRetest || hs.recoveries >= hs.config.MaxRecoveries {
+			return nil, fmt.Errorf("entropy source is unhealthy: %w", hs.failure)
+		}
+		// Recover by requiring a fresh startup test
+		hs.recoveries++
+		hs.restart()
+	}
+
+	if !hs.started {
+		if err := hs.startup(); err != nil {
+			hs.fail(err)
+			return nil, fmt.Errorf("entropy source startup test failed: %w", err)
+		}
+	}
+
+	buf, err := hs.source.GenerateBytes(n)
+	if err != nil {
+		hs.fail(err)
+		return nil, err
+	}
+	if err := hs.tester.feed(buf); err != nil {
+		clear(buf)
+		hs.fail(err)
+		return nil, err
+	}
+
+	hs.stats.generated.Add(uint64(n))
+	return buf, nil
+}
+
+// startup runs the startup self-test: the tests must reject a stuck input,
+// and then pass over startupSamples fresh bytes, which are discarded
+func (hs *healthTestedSource) startup() error {
+	check := healthTester{config: hs.config}
+	if check.feed(bytes.Repeat([]byte{0x5a}, hs.config.RCTCutoff)) == nil {
+		return errors.New("health tests accepted a stuck input")
+	}
+
+	hs.tester.reset()
+	buf, err := hs.source.GenerateBytes(startupSamples)
+	if err != nil {
+		return err
+	}
+	defer clear(buf)
+	if err := hs.tester.feed(buf); err != nil {
+		return err
+	}
+
+	hs.started = true
+	return nil
+}
+
+// fail latches a failure
+func (hs *healthTestedSource) fail(err error) {
+	hs.failure = err
+	hs.stats.failures.Add(1)
+}
+
+// restart clears the failure and requires a new startup test
+func (hs *healthTestedSource) restart() {
+	hs.failure = nil
+	hs.started = false
+	hs.tester.reset()
+	hs.source.Reset()
+}
+
+// GenerateUint64 generates a health-tested random uint64
+func (hs *healthTestedSource) GenerateUint64() (uint64, error) {
+	return uint64FromSource(hs)
+}
+
+// Health returns the latched failure, if any
+func (hs *healthTestedSource) Health() error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if hs.failure != nil {
+		return hs.failure
+	}
+	return hs.source.Health()
+}
+
+// Reset clears a latched failure and the recovery count and reruns the
+// startup self-test
+func (hs *healthTestedSource) Reset() error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.recoveries = 0
+	hs.restart()
+	if err := hs.startup(); err != nil {
+		hs.fail(err)
+		return fmt.Errorf("entropy source startup test failed: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the bytes released and the number of failures
+func (hs *healthTestedSource) Stats() (generated, errors uint64) {
+	return hs.stats.generated.Load(), hs.stats.failures.Load()
+}
+
+// Close closes the wrapped source if it implements io.Closer
+func (hs *healthTestedSource) Close() error {
+	if c, ok := hs.source.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Identifier constants
+const (
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	ulidRandomBytes   = 10
+	maxULIDTime       = 1<<48 - 1
+)
+
+// ErrULIDOverflow is returned when monotonic ULID generation exhausts the
+// random component within a single millisecond
+var ErrULIDOverflow = errors.New("ulid monotonic random component overflow")
+
+// formatUUID renders 16 bytes in the canonical 8-4-4-4-12 form
+func formatUUID(u [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// generateUUIDv4 generates a random (version 4) UUID
+func (cg *CryptoGenerator) generateUUIDv4(ctx context.Context, config *GeneratorConfig) (string, error) {
+	raw, err := cg.entropy.GenerateBytes(16)
+	if err != nil {
+		return "", fmt.Errorf("generating uuid: %w", err)
+	}
+
+	var u [16]byte
+	copy(u[:], raw)
+	u[6] = u[6]&0x0f | 0x40 // Version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+	return formatUUID(u), nil
+}
+
+// generateUUIDv7 generates a time-ordered (version 7) UUID as defined in
+// RFC 9562: 48 bits of Unix milliseconds followed by 74 random bits
+func (cg *CryptoGenerator) generateUUIDv7(ctx context.Context, config *GeneratorConfig) (string, error) {
+	raw, err := cg.entropy.GenerateBytes(10)
+	if err != nil {
+		return "", fmt.Errorf("generating uuid: %w", err)
+	}
+
+	var u [16]byte
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixMilli()))
+	copy(u[0:6], ts[2:8])
+	copy(u[6:], raw)
+	u[6] = u[6]&0x0f | 0x70 // Version 7
+	u[8] = u[8]&0x3f | 0x80 // RFC 9562 variant
+	return formatUUID(u), nil
+}
+
+// encodeULID renders a 48-bit millisecond timestamp and 80 random bits as a
+// 26-character Crockford base32 ULID
+func encodeULID(ms uint64, random [ulidRandomBytes]byte) string {
+	var raw [16]byte
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(raw[0:6], ts[2:8])
+	copy(raw[6:], random[:])
+
+	// 128 bits are encoded as 26 characters of 5 bits, the first holding
+	// only the top 3 bits
+	hi := binary.BigEndian.Uint64(raw[0:8])
+	lo := binary.BigEndian.Uint64(raw[8:16])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// generateULID generates a ULID. In monotonic mode, ULIDs created within the
+// same millisecond increment the random component of the previous one so
+// that they sort in generation order.
+func (cg *CryptoGenerator) generateULID(ctx context.Context, config *GeneratorConfig) (string, error) {
+	ms := uint64(time.Now().UnixMilli())
+	if ms > maxULIDTime {
+		return "", errors.New("timestamp exceeds ulid range")
+	}
+
+	if !config.Monotonic {
+		raw, err := cg.entropy.GenerateBytes(ulidRandomBytes)
+		if err != nil {
+			return "", fmt.Errorf("generating ulid: %w", err)
+		}
+		var random [ulidRandomBytes]byte
+		copy(random[:], raw)
+		return encodeULID(ms, random), nil
+	}
+
+	cg.ulid.Lock()
+	defer cg.ulid.Unlock()
+
+	if ms <= cg.ulid.lastMS {
+		// Same (or earlier, if the clock stepped back) millisecond
+		if !incrementBytes(cg.ulid.last[:]) {
+			return "", ErrULIDOverflow
+		}
+		return encodeULID(cg.ulid.lastMS, cg.ulid.last), nil
+	}
+
+	raw, err := cg.entropy.GenerateBytes(ulidRandomBytes)
+	if err != nil {
+		return "", fmt.Errorf("generating ulid: %w", err)
+	}
+	cg.ulid.lastMS = ms
+	copy(cg.ulid.last[:], raw)
+	return encodeULID(ms, cg.ulid.last), nil
+}
+
+// incrementBytes adds one to a big-endian number, reporting false on
+// overflow
+func incrementBytes(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
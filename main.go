@@ -3,22 +3,25 @@ package main
 import (
 	"cmp"
 	"context"
-	"crypto/rand"
-	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"iter"
 	"os"
+	"os/signal"
 	"runtime"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/sys/cpu"
+	"gopkg.in/yaml.v3"
 )
 
 // Compile-time constants optimized by the compiler
@@ -27,6 +30,15 @@ const (
 	_ GeneratorType = iota
 	GeneratorHyphenated
 	GeneratorCompact
+	GeneratorPassphrase
+	GeneratorPattern
+	GeneratorPronounceable
+	GeneratorToken
+	GeneratorAPIKey
+	GeneratorUUIDv4
+	GeneratorUUIDv7
+	GeneratorULID
+	GeneratorMnemonic
 
 	// Character sets as compile-time constants for better optimization
 	lowerChars = "abcdefghijklmnopqrstuvwxyz"
@@ -41,6 +53,15 @@ const (
 	defaultWorkerPoolSize   = 8
 	bufferPoolSize          = 1024
 	maxBatchSize            = 1000
+	maxPassphraseWords      = 64
+
+	// Default length of compact and pronounceable strings
+	defaultLength = 15
+
+	// Default hyphenated layout (6char-6char-6char)
+	defaultGroups    = 3
+	defaultGroupSize = 6
+	defaultSeparator = "-"
 
 	// Security constants
 	minEntropyBits    = 128
@@ -62,6 +83,24 @@ func (g GeneratorType) String() string {
 		return "hyphenated"
 	case GeneratorCompact:
 		return "compact"
+	case GeneratorPassphrase:
+		return "passphrase"
+	case GeneratorPattern:
+		return "pattern"
+	case GeneratorPronounceable:
+		return "pronounceable"
+	case GeneratorToken:
+		return "token"
+	case GeneratorAPIKey:
+		return "apikey"
+	case GeneratorUUIDv4:
+		return "uuid4"
+	case GeneratorUUIDv7:
+		return "uuid7"
+	case GeneratorULID:
+		return "ulid"
+	case GeneratorMnemonic:
+		return "mnemonic"
 	default:
 		return "unknown"
 	}
@@ -74,6 +113,24 @@ func ParseGeneratorType(s string) (GeneratorType, error) {
 		return GeneratorHyphenated, nil
 	case "compact", "c":
 		return GeneratorCompact, nil
+	case "passphrase", "p":
+		return GeneratorPassphrase, nil
+	case "pattern", "m":
+		return GeneratorPattern, nil
+	case "pronounceable", "s":
+		return GeneratorPronounceable, nil
+	case "token", "k":
+		return GeneratorToken, nil
+	case "apikey", "a":
+		return GeneratorAPIKey, nil
+	case "uuid4", "uuid":
+		return GeneratorUUIDv4, nil
+	case "uuid7":
+		return GeneratorUUIDv7, nil
+	case "ulid":
+		return GeneratorULID, nil
+	case "mnemonic", "bip39":
+		return GeneratorMnemonic, nil
 	default:
 		return 0, fmt.Errorf("invalid generator type: %q", s)
 	}
@@ -136,7 +193,25 @@ type GeneratorConfig struct {
 	Length       int
 	Count        int
 	Charset      *CharacterSet
+	Policy       PasswordPolicy
+	Pattern      *Pattern
+	Bits         int               // Token strength in bits
+	Encoding     TokenEncoding     // Token text encoding
+	Prefix       string            // API key prefix
+	Checksum     ChecksumAlgorithm // API key checksum
+	Monotonic    bool              // ULIDs sort in generation order within a millisecond (generated sequentially)
+	Groups       int               // Hyphenated group count (default 3)
+	GroupSize    int               // Hyphenated group size (default 6)
+	Wordlist     *WordList         // Passphrase word list (EFF large list if nil)
+	Words        int
+	Separator    string // Hyphenated group or passphrase word separator
+	Capitalize   bool
+	AppendDigit  bool
+	MinEntropy   float64   // Reject configurations below this many bits (0 = disabled)
+	BreachDB     *BreachDB // Regenerate candidates found in this corpus (optional)
 	Parallel     bool
+	Unordered    bool        // Streamed strings may leave generation order
+	OnError      ErrorPolicy // Handling of strings that fail to generate
 	Workers      int
 	BatchSize    int
 	MemoryPool   bool
@@ -147,18 +222,94 @@ type GeneratorConfig struct {
 func (gc *GeneratorConfig) Validate() error {
 	var errs []error
 
-	if gc.Length <= 0 || gc.Length > maxStringLength {
-		errs = append(errs, fmt.Errorf("invalid length: %d (must be 1-%d)", gc.Length, maxStringLength))
+	if gc.Count <= 0 {
+		errs = append(errs, fmt.Errorf("invalid count: %d (must be positive)", gc.Count))
 	}
 
-	if gc.Count <= 0 || gc.Count > maxBatchSize {
-		errs = append(errs, fmt.Errorf("invalid count: %d (must be 1-%d)", gc.Count, maxBatchSize))
+	switch gc.Type {
+	case GeneratorPassphrase:
+		if gc.Words <= 0 || gc.Words > maxPassphraseWords {
+			errs = append(errs, fmt.Errorf("invalid word count: %d (must be 1-%d)", gc.Words, maxPassphraseWords))
+		}
+
+		if gc.Wordlist != nil && gc.Wordlist.Len() < 2 {
+			errs = append(errs, errors.New("wordlist must contain at least 2 words"))
+		}
+	case GeneratorPattern:
+		if gc.Pattern == nil || gc.Pattern.Len() == 0 {
+			errs = append(errs, errors.New("pattern cannot be empty"))
+		}
+	case GeneratorPronounceable:
+		if gc.Length <= 0 || gc.Length > maxStringLength {
+			errs = append(errs, fmt.Errorf("invalid length: %d (must be 1-%d)", gc.Length, maxStringLength))
+		}
+	case GeneratorToken:
+		if gc.Bits < minTokenBits || gc.Bits > maxTokenBits || gc.Bits%8 != 0 {
+			errs = append(errs, fmt.Errorf("invalid token bits: %d (must be a multiple of 8 in %d-%d)", gc.Bits, minTokenBits, maxTokenBits))
+		}
+	case GeneratorMnemonic:
+		if !validMnemonicBits(gc.Bits) {
+			errs = append(errs, fmt.Errorf("invalid mnemonic bits: %d (must be a multiple of %d in %d-%d)", gc.Bits, mnemonicBitsStep, minMnemonicBits, maxMnemonicBits))
+		}
+	case GeneratorUUIDv4, GeneratorUUIDv7, GeneratorULID:
+		// Fixed-format identifiers need no further configuration
+	case GeneratorAPIKey:
+		if gc.Length <= 0 || gc.Length > maxStringLength-maxPrefixLen-checksumLength {
+			errs = append(errs, fmt.Errorf("invalid length: %d (must be 1-%d)", gc.Length, maxStringLength-maxPrefixLen-checksumLength))
+		}
+
+		if err := validatePrefix(gc.Prefix); err != nil {
+			errs = append(errs, err)
+		}
+
+		if err := gc.validateCharset(); err != nil {
+			errs = append(errs, err)
+		}
+	case GeneratorHyphenated:
+		if gc.Groups < 0 {
+			errs = append(errs, fmt.Errorf("invalid group count: %d", gc.Groups))
+		}
+		if gc.GroupSize < 0 {
+			errs = append(errs, fmt.Errorf("invalid group size: %d", gc.GroupSize))
+		}
+
+		groups, size, sep := gc.hyphenatedLayout()
+		if total := groups*size + (groups-1)*len(sep); total > maxStringLength {
+			errs = append(errs, fmt.Errorf("hyphenated layout too long: %d (max %d)", total, maxStringLength))
+		}
+		if gc.Length != 0 && gc.Length != groups*size {
+			errs = append(errs, fmt.Errorf("length %d does not match the hyphenated layout of %d groups of %d characters; set the groups and group size instead", gc.Length, groups, size))
+		}
+
+		if err := gc.validateCharset(); err != nil {
+			errs = append(errs, err)
+		}
+	default:
+		if gc.Length <= 0 || gc.Length > maxStringLength {
+			errs = append(errs, fmt.Errorf("invalid length: %d (must be 1-%d)", gc.Length, maxStringLength))
+		}
+
+		if err := gc.validateCharset(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	if gc.Charset == nil || gc.Charset.Len() == 0 {
-		errs = append(errs, errors.New("charset cannot be empty"))
-	} else if gc.Charset.Len() > 256 {
-		errs = append(errs, errors.New("charset too large (max 256 characters)"))
+	if gc.MinEntropy < 0 {
+		errs = append(errs, fmt.Errorf("invalid minimum entropy: %g", gc.MinEntropy))
+	} else if gc.MinEntropy > 0 && len(errs) == 0 {
+		if bits := gc.Entropy(); bits < gc.MinEntropy {
+			errs = append(errs, fmt.Errorf("entropy %.2f bits is below the required minimum of %g bits", bits, gc.MinEntropy))
+		}
+	}
+
+	if !gc.Policy.IsZero() {
+		if gc.Type != GeneratorCompact {
+			errs = append(errs, fmt.Errorf("character policy is not supported for %s format", gc.Type))
+		} else if gc.Charset != nil && gc.Charset.Len() > 0 {
+			if err := gc.Policy.Validate(gc.Length, gc.Charset); err != nil {
+				errs = append(errs, err)
+			}
+		}
 	}
 
 	if gc.Workers <= 0 {
@@ -174,6 +325,42 @@ func (gc *GeneratorConfig) Validate() error {
 	return nil
 }
 
+// validateCharset checks that the configured charset is usable
+func (gc *GeneratorConfig) validateCharset() error {
+	if gc.Charset == nil || gc.Charset.Len() == 0 {
+		return errors.New("charset cannot be empty")
+	} else if gc.Charset.Len() > 256 {
+		return errors.New("charset too large (max 256 characters)")
+	}
+	return nil
+}
+
+// hyphenatedLayout returns the hyphenated group count, group size and
+// separator, substituting the 6-6-6 defaults for zero values
+func (gc *GeneratorConfig) hyphenatedLayout() (groups, size int, sep string) {
+	return cmp.Or(gc.Groups, defaultGroups), cmp.Or(gc.GroupSize, defaultGroupSize), cmp.Or(gc.Separator, defaultSeparator)
+}
+
+// validateLayout checks layout settings taken from flags or a bundle spec.
+// These are always explicit, so a zero group count or size, or an empty
+// separator, is an error rather than a request for the defaults that zero
+// values select in a GeneratorConfig.
+func validateLayout(genType GeneratorType, groups, groupSize int, separator string) error {
+	var errs []error
+	if genType == GeneratorHyphenated {
+		if groups == 0 {
+			errs = append(errs, fmt.Errorf("invalid group count: %d", groups))
+		}
+		if groupSize == 0 {
+			errs = append(errs, fmt.Errorf("invalid group size: %d", groupSize))
+		}
+	}
+	if (genType == GeneratorHyphenated || genType == GeneratorPassphrase) && separator == "" {
+		errs = append(errs, errors.New("separator cannot be empty"))
+	}
+	return errors.Join(errs...)
+}
+
 // ByteBuffer represents a reusable byte buffer with pooling
 type ByteBuffer struct {
 	buf []byte
@@ -250,61 +437,6 @@ func (bp *BufferPool) Put(bb *ByteBuffer) {
 	}
 }
 
-// EntropySource represents a cryptographically secure entropy source
-type EntropySource struct {
-	health atomic.Bool
-	stats  struct {
-		generated atomic.Uint64
-		errors    atomic.Uint64
-	}
-}
-
-// NewEntropySource creates a new entropy source
-func NewEntropySource() *EntropySource {
-	es := &EntropySource{}
-	es.health.Store(true)
-	return es
-}
-
-// GenerateBytes generates cryptographically secure random bytes
-func (es *EntropySource) GenerateBytes(n int) ([]byte, error) {
-	if !es.health.Load() {
-		return nil, errors.New("entropy source is unhealthy")
-	}
-
-	buf := make([]byte, n)
-	_, err := rand.Read(buf)
-	if err != nil {
-		es.stats.errors.Add(1)
-		es.health.Store(false)
-		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
-	}
-
-	es.stats.generated.Add(uint64(n))
-	return buf, nil
-}
-
-// GenerateUint64 generates a cryptographically secure random uint64
-func (es *EntropySource) GenerateUint64() (uint64, error) {
-	bytes, err := es.GenerateBytes(8)
-	if err != nil {
-		return 0, err
-	}
-
-	// Convert bytes to uint64 using safe binary encoding
-	return binary.LittleEndian.Uint64(bytes), nil
-}
-
-// Health returns the health status of the entropy source
-func (es *EntropySource) Health() bool {
-	return es.health.Load()
-}
-
-// Stats returns statistics about the entropy source
-func (es *EntropySource) Stats() (generated, errors uint64) {
-	return es.stats.generated.Load(), es.stats.errors.Load()
-}
-
 // Generator represents a generic string generator using type parameters
 type Generator[T any] interface {
 	Generate(ctx context.Context, config *GeneratorConfig) (T, error)
@@ -314,20 +446,35 @@ type Generator[T any] interface {
 
 // CryptoGenerator implements a cryptographically secure string generator
 type CryptoGenerator struct {
-	entropy    *EntropySource
+	entropy    EntropySource
 	bufferPool *BufferPool
 	workers    chan struct{}
 	stats      struct {
 		generated atomic.Uint64
 		errors    atomic.Uint64
 		duration  atomic.Uint64 // in nanoseconds
+		breached  atomic.Uint64 // candidates rejected by breach screening
+		skipped   atomic.Uint64 // strings left out under the skip error policy
+		retries   atomic.Uint64 // failed attempts retried under the retry error policy
+	}
+	ulid struct {
+		sync.Mutex
+		lastMS uint64
+		last   [ulidRandomBytes]byte
 	}
 }
 
-// NewCryptoGenerator creates a new cryptographic string generator
+// NewCryptoGenerator creates a new cryptographic string generator drawing
+// from the default entropy source
 func NewCryptoGenerator(workerLimit int) *CryptoGenerator {
+	return NewCryptoGeneratorWithEntropy(workerLimit, NewEntropySource())
+}
+
+// NewCryptoGeneratorWithEntropy creates a new cryptographic string generator
+// drawing from the given entropy source
+func NewCryptoGeneratorWithEntropy(workerLimit int, source EntropySource) *CryptoGenerator {
 	return &CryptoGenerator{
-		entropy:    NewEntropySource(),
+		entropy:    source,
 		bufferPool: NewBufferPool(1024),
 		workers:    make(chan struct{}, workerLimit),
 	}
@@ -352,19 +499,7 @@ func (cg *CryptoGenerator) Generate(ctx context.Context, config *GeneratorConfig
 		defer func() { <-cg.workers }()
 	}
 
-	var result string
-	var err error
-
-	switch config.Type {
-	case GeneratorHyphenated:
-		result, err = cg.generateHyphenatedString(ctx, config)
-	case GeneratorCompact:
-		result, err = cg.generateCompactString(ctx, config)
-	default:
-		// Fallback to hyphenated
-		result, err = cg.generateHyphenatedString(ctx, config)
-	}
-
+	result, err := cg.generateScreened(ctx, config)
 	if err != nil {
 		cg.stats.errors.Add(1)
 		return "", err
@@ -374,74 +509,201 @@ func (cg *CryptoGenerator) Generate(ctx context.Context, config *GeneratorConfig
 	return result, nil
 }
 
-// GenerateBatch generates multiple secure random strings concurrently
+// GeneratedString is a generated string with its position in generation
+// order and the time it was generated. Skipped failures and unordered
+// streams leave the position different from the output position.
+type GeneratedString struct {
+	Value       string
+	Index       int
+	GeneratedAt time.Time
+}
+
+// generateTimed generates the string at position index under the error
+// policy and records when it was produced
+func (cg *CryptoGenerator) generateTimed(ctx context.Context, config *GeneratorConfig, index int) (GeneratedString, error) {
+	value, err := cg.generateWithRetries(ctx, config)
+	if err != nil {
+		return GeneratedString{}, err
+	}
+	return GeneratedString{Value: value, Index: index, GeneratedAt: time.Now().UTC()}, nil
+}
+
+// GenerateBatch generates multiple secure random strings concurrently.
+// Failures are handled by config.OnError. If fewer than Count strings are
+// produced, the error is a *ShortCountError, returned together with the
+// strings produced when failures were skipped.
 func (cg *CryptoGenerator) GenerateBatch(ctx context.Context, config *GeneratorConfig) ([]string, error) {
+	generated, err := cg.GenerateBatchTimed(ctx, config)
+	if generated == nil {
+		return nil, err
+	}
+	results := make([]string, len(generated))
+	for i, g := range generated {
+		results[i] = g.Value
+	}
+	return results, err
+}
+
+// GenerateBatchTimed is GenerateBatch with the generation time of each
+// string
+func (cg *CryptoGenerator) GenerateBatchTimed(ctx context.Context, config *GeneratorConfig) ([]GeneratedString, error) {
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	if config.Count > maxBatchSize {
+		return nil, fmt.Errorf("invalid config: count %d exceeds the batch limit of %d; stream larger counts", config.Count, maxBatchSize)
+	}
 
-	results := make([]string, config.Count)
+	results := make([]GeneratedString, config.Count)
+	failures := make([]error, config.Count)
 
-	if config.Count == 1 || !config.Parallel {
-		// Sequential generation for small batches
-		for i := 0; i < config.Count; i++ {
-			result, err := cg.Generate(ctx, config)
-			if err != nil {
-				return nil, fmt.Errorf("generating string %d: %w", i, err)
+	if config.Count == 1 || !config.Parallel || config.Monotonic {
+		// Sequential generation for small batches, and for monotonic ULIDs,
+		// whose order is only kept when each is stored as it is taken
+		for i := range results {
+			results[i], failures[i] = cg.generateTimed(ctx, config, i)
+			if failures[i] != nil && !config.OnError.skippable(ctx) {
+				return nil, &ShortCountError{Requested: config.Count, Err: fmt.Errorf("generating string %d: %w", i, failures[i])}
 			}
-			results[i] = result
 		}
-		return results, nil
+		return cg.collectResults(config, results, failures)
 	}
 
 	// Parallel generation using errgroup
-	g, ctx := errgroup.WithContext(ctx)
+	g, gctx := errgroup.WithContext(ctx)
 	g.SetLimit(config.Workers)
 
-	for i := 0; i < config.Count; i++ {
-		i := i // Capture loop variable
+	for i := range results {
 		g.Go(func() error {
-			result, err := cg.Generate(ctx, config)
-			if err != nil {
-				return fmt.Errorf("generating string %d: %w", i, err)
+			results[i], failures[i] = cg.generateTimed(gctx, config, i)
+			if failures[i] != nil && !config.OnError.skippable(gctx) {
+				return fmt.Errorf("generating string %d: %w", i, failures[i])
 			}
-			results[i] = result
 			return nil
 		})
 	}
 
 	if err := g.Wait(); err != nil {
-		return nil, err
+		return nil, &ShortCountError{Requested: config.Count, Err: err}
 	}
 
-	return results, nil
+	return cg.collectResults(config, results, failures)
 }
 
-// GenerateStream generates strings as an iterator using Go 1.25 iter package
+// collectResults drops the skipped strings of a batch, reporting them with
+// a *ShortCountError
+func (cg *CryptoGenerator) collectResults(config *GeneratorConfig, results []GeneratedString, failures []error) ([]GeneratedString, error) {
+	produced := results[:0]
+	var last error
+	for i, result := range results {
+		if failures[i] != nil {
+			last = fmt.Errorf("generating string %d: %w", i, failures[i])
+			continue
+		}
+		produced = append(produced, result)
+	}
+
+	skipped := config.Count - len(produced)
+	if skipped == 0 {
+		return produced, nil
+	}
+	cg.stats.skipped.Add(uint64(skipped))
+	return produced, &ShortCountError{Requested: config.Count, Produced: len(produced), Skipped: skipped, Err: last}
+}
+
+// GenerateStream generates strings as an iterator using Go 1.25 iter package.
+// Unlike GenerateBatch, the count is not capped and memory stays bounded:
+// with Parallel set, a worker pipeline runs at most a fixed window ahead of
+// the consumer, which applies backpressure simply by consuming slowly.
+// Failures are handled by config.OnError; if fewer than Count strings are
+// yielded, the stream ends with a *ShortCountError.
 func (cg *CryptoGenerator) GenerateStream(ctx context.Context, config *GeneratorConfig) iter.Seq2[string, error] {
 	return func(yield func(string, error) bool) {
-		for i := 0; i < config.Count; i++ {
-			select {
-			case <-ctx.Done():
-				if !yield("", ctx.Err()) {
-					return
-				}
+		for g, err := range cg.GenerateStreamTimed(ctx, config) {
+			if !yield(g.Value, err) {
 				return
-			default:
+			}
+		}
+	}
+}
+
+// GenerateStreamTimed is GenerateStream with the generation time of each
+// string
+func (cg *CryptoGenerator) GenerateStreamTimed(ctx context.Context, config *GeneratorConfig) iter.Seq2[GeneratedString, error] {
+	return func(yield func(GeneratedString, error) bool) {
+		if err := config.Validate(); err != nil {
+			yield(GeneratedString{}, fmt.Errorf("invalid config: %w", err))
+			return
+		}
+
+		produced, skipped := 0, 0
+		stopped := false
+		var failure error
+		emit := func(result GeneratedString, err error) bool {
+			if err == nil {
+				produced++
+				stopped = !yield(result, nil)
+				return !stopped
 			}
 
-			result, err := cg.Generate(ctx, config)
-			if !yield(result, err) {
-				return
+			failure = err
+			if config.OnError.skippable(ctx) {
+				skipped++
+				cg.stats.skipped.Add(1)
+				return true
 			}
+			return false
+		}
+
+		if config.Count == 1 || !config.Parallel || config.Workers == 1 || config.Monotonic {
+			cg.streamSequential(ctx, config, emit)
+		} else {
+			cg.streamParallel(ctx, config, emit)
+		}
+
+		if !stopped && produced < config.Count {
+			yield(GeneratedString{}, &ShortCountError{Requested: config.Count, Produced: produced, Skipped: skipped, Err: failure})
 		}
 	}
 }
 
+// generateByType dispatches to the generator for the configured type
+func (cg *CryptoGenerator) generateByType(ctx context.Context, config *GeneratorConfig) (string, error) {
+	switch config.Type {
+	case GeneratorHyphenated:
+		return cg.generateHyphenatedString(ctx, config)
+	case GeneratorCompact:
+		return cg.generateCompactString(ctx, config)
+	case GeneratorPassphrase:
+		return cg.generatePassphrase(ctx, config)
+	case GeneratorPattern:
+		return cg.generatePatternString(ctx, config)
+	case GeneratorPronounceable:
+		return cg.generatePronounceable(ctx, config)
+	case GeneratorToken:
+		return cg.generateToken(ctx, config)
+	case GeneratorAPIKey:
+		return cg.generateAPIKey(ctx, config)
+	case GeneratorUUIDv4:
+		return cg.generateUUIDv4(ctx, config)
+	case GeneratorUUIDv7:
+		return cg.generateUUIDv7(ctx, config)
+	case GeneratorULID:
+		return cg.generateULID(ctx, config)
+	case GeneratorMnemonic:
+		return cg.generateMnemonic(ctx, config)
+	default:
+		// Fallback to hyphenated
+		return cg.generateHyphenatedString(ctx, config)
+	}
+}
+
 // generateHyphenatedString generates a hyphenated format string
 func (cg *CryptoGenerator) generateHyphenatedString(ctx context.Context, config *GeneratorConfig) (string, error) {
+	groups, size, sep := config.hyphenatedLayout()
+
 	// Use functional programming style with pipeline
-	parts := make([]string, 3)
+	parts := make([]string, groups)
 
 	// Generate parts concurrently if context allows
 	g, ctx := errgroup.WithContext(ctx)
@@ -449,7 +711,7 @@ func (cg *CryptoGenerator) generateHyphenatedString(ctx context.Context, config
 	for i := range parts {
 		i := i // Capture loop variable
 		g.Go(func() error {
-			part, err := cg.generateSecureString(ctx, 6, config.Charset)
+			part, err := cg.generateSecureString(ctx, size, config.Charset)
 			if err != nil {
 				return fmt.Errorf("generating part %d: %w", i, err)
 			}
@@ -462,16 +724,17 @@ func (cg *CryptoGenerator) generateHyphenatedString(ctx context.Context, config
 		return "", err
 	}
 
-	return strings.Join(parts, "-"), nil
+	return strings.Join(parts, sep), nil
 }
 
 // generateCompactString generates a compact format string
 func (cg *CryptoGenerator) generateCompactString(ctx context.Context, config *GeneratorConfig) (string, error) {
-	return cg.generateSecureString(ctx, config.Length, config.Charset)
+	return cg.generatePolicyString(ctx, config.Length, config.Charset, config.Policy)
 }
 
-// generateSecureString generates a cryptographically secure random string
-// using constant-time operations when possible
+// generateSecureString generates a cryptographically secure random string.
+// Entropy is read in one chunk sized for the whole string and consumed a few
+// bits per character.
 func (cg *CryptoGenerator) generateSecureString(ctx context.Context, length int, charset *CharacterSet) (string, error) {
 	if length <= 0 {
 		return "", errors.New("length must be positive")
@@ -482,61 +745,27 @@ func (cg *CryptoGenerator) generateSecureString(ctx context.Context, length int,
 	defer cg.bufferPool.Put(buffer)
 
 	buffer.Grow(length)
+	defer func() { clear(buffer.buf) }() // Wipe the characters before reuse
+
+	n := uint64(charset.Len())
+	sampler := newIndexSampler(cg.entropy, sampleBytes(n, length))
+	defer sampler.wipe()
 
-	// Generate random indices for character selection
-	indices := make([]uint64, length)
-	for i := range indices {
+	for range length {
 		select {
 		case <-ctx.Done():
 			return "", ctx.Err()
 		default:
 		}
 
-		randVal, err := cg.entropy.GenerateUint64()
+		idx, err := sampler.index(n)
 		if err != nil {
-			return "", fmt.Errorf("generating random value: %w", err)
-		}
-		indices[i] = randVal
-	}
-
-	// Select characters using constant-time operations when possible
-	result := make([]byte, length)
-	if charset.mask != 0 {
-		// Optimized path for power-of-2 character sets
-		for i, idx := range indices {
-			result[i] = charset.At(idx)
-		}
-	} else {
-		// Fallback with rejection sampling for uniform distribution
-		for i, idx := range indices {
-			// Use rejection sampling to ensure uniform distribution
-			maxValid := ^uint64(0) - (^uint64(0) % uint64(charset.Len()))
-			retries := 0
-			const maxRetries = 10 // Prevent infinite loops
-
-			for idx >= maxValid {
-				if retries >= maxRetries {
-					return "", errors.New("too many retries in random sampling - possible attack")
-				}
-				var err error
-				idx, err = cg.entropy.GenerateUint64()
-				if err != nil {
-					return "", fmt.Errorf("generating uniform random value: %w", err)
-				}
-				retries++
-			}
-			result[i] = charset.At(idx)
+			return "", err
 		}
+		buffer.buf = append(buffer.buf, charset.At(idx))
 	}
 
-	// Clear sensitive data from memory
-	defer func() {
-		for i := range indices {
-			indices[i] = 0
-		}
-	}()
-
-	return string(result), nil
+	return buffer.String(), nil
 }
 
 // Stats returns generator statistics
@@ -577,22 +806,75 @@ func main() {
 		Long: `Generate cryptographically secure passwords.
 
 Formats:
-  hyphenated  6char-6char-6char (default)
-  compact     custom length string`,
+  hyphenated     6char-6char-6char (default, see --groups/--group-size)
+  compact        custom length string
+  passphrase     words from the EFF large wordlist
+  pattern        template such as "Cvcv-9999-xxxx" (see --pattern)
+  pronounceable  alternating consonant and vowel syllables
+  token          raw random bits in hex, base32, base58 or base64url
+  apikey         prefixed key with a CRC32 checksum, e.g. acme_live_<body><crc>
+  uuid4, uuid7   RFC 9562 random or time-ordered UUIDs
+  ulid           time-ordered ULIDs (see --monotonic)
+  mnemonic       BIP39 mnemonic of 12-24 words (--bits 128-256)`,
 		Version: version,
 		RunE:    app.runCommand,
 	}
 
 	// Configure flags with advanced validation
-	rootCmd.Flags().StringP("type", "t", "hyphenated", "Output format (hyphenated|compact)")
-	rootCmd.Flags().IntP("length", "l", 15, "Length for compact format")
+	rootCmd.Flags().StringP("type", "t", "hyphenated", "Output format (hyphenated|compact|passphrase|pattern|pronounceable|token|apikey|uuid4|uuid7|ulid|mnemonic)")
+	rootCmd.Flags().IntP("length", "l", defaultLength, "Length for compact and pronounceable formats, or API key body (hyphenated: must equal groups x group size)")
 	rootCmd.Flags().IntP("count", "c", 1, "Number of passwords")
-	rootCmd.Flags().StringP("charset", "s", alphanumericChars, "Character set")
+	rootCmd.Flags().StringP("charset", "s", alphanumericChars, "Character set: preset ("+strings.Join(CharsetPresets(), "|")+") or characters and ranges like a-z0-9_")
+	rootCmd.Flags().BoolP("exclude-ambiguous", "", false, "Remove look-alike characters ("+ambiguousChars+") from the charset")
+	rootCmd.Flags().IntP("min-upper", "", 0, "Minimum uppercase letters for compact format")
+	rootCmd.Flags().IntP("min-lower", "", 0, "Minimum lowercase letters for compact format")
+	rootCmd.Flags().IntP("min-digit", "", 0, "Minimum digits for compact format")
+	rootCmd.Flags().IntP("min-symbol", "", 0, "Minimum symbols for compact format")
+	rootCmd.Flags().StringP("forbid", "", "", "Characters that must not appear")
+	rootCmd.Flags().IntP("max-repeat", "", 0, "Maximum run of identical characters (0 = unlimited)")
+	rootCmd.Flags().StringP("pattern", "", "", "Template for pattern format (A/a upper/lower, C/c consonant, V/v vowel, X/x hex, 9 digit, ! symbol, * charset, \\ escape)")
+	rootCmd.Flags().IntP("bits", "", defaultTokenBits, "Token or mnemonic strength in bits")
+	rootCmd.Flags().StringP("encoding", "", "hex", "Token encoding (hex|base32|base32-nopad|base58|base64url)")
+	rootCmd.Flags().StringP("prefix", "", "", "Prefix for apikey format (e.g. acme_live_)")
+	rootCmd.Flags().StringP("checksum", "", "crc32", "Checksum for apikey format (crc32|crc32c|none)")
+	rootCmd.Flags().BoolP("monotonic", "", false, "Increment ULIDs generated within the same millisecond")
+	rootCmd.Flags().IntP("words", "", 6, "Number of words for passphrase format")
+	rootCmd.Flags().IntP("groups", "", defaultGroups, "Number of groups for hyphenated format")
+	rootCmd.Flags().IntP("group-size", "", defaultGroupSize, "Characters per group for hyphenated format")
+	rootCmd.Flags().StringP("separator", "", defaultSeparator, "Separator for hyphenated groups and passphrase words")
+	rootCmd.Flags().BoolP("capitalize", "", false, "Capitalize passphrase words")
+	rootCmd.Flags().BoolP("append-digit", "", false, "Append a random digit to the passphrase")
+	rootCmd.Flags().StringP("wordlist", "", "", "Wordlist file for passphrase format (default: embedded EFF large list)")
 	rootCmd.Flags().BoolP("parallel", "p", true, "Parallel generation")
 	rootCmd.Flags().IntP("workers", "w", runtime.NumCPU(), "Worker threads")
-	rootCmd.Flags().BoolP("stats", "", false, "Show statistics")
-	rootCmd.Flags().BoolP("stream", "", false, "Stream output")
-	rootCmd.Flags().DurationP("timeout", "", 30*time.Second, "Timeout")
+	rootCmd.Flags().BoolP("stats", "", false, "Show statistics on stderr as a JSON object (YAML with --format yaml)")
+	rootCmd.Flags().StringP("format", "f", "plain", "Output format; structured formats add per-string metadata (plain|json|ndjson|csv|yaml)")
+	rootCmd.Flags().BoolP("show-entropy", "", false, "Show entropy of each generated string")
+	rootCmd.Flags().Float64P("min-entropy", "", 0, "Refuse configurations below this many bits of entropy")
+	rootCmd.Flags().BoolP("stream", "", false, "Stream output without the batch limit of 1000")
+	rootCmd.Flags().BoolP("ordered", "", true, "Keep streamed output in generation order (structured formats report the generation index either way)")
+	rootCmd.Flags().StringP("on-error", "", "fail", "When a string fails to generate: stop, leave it out, or retry it N times (fail|skip|retry=N)")
+	rootCmd.Flags().StringP("breach-db", "", "", "Sorted HIBP hash file; regenerate candidates found in it")
+	rootCmd.Flags().StringP("breach-hash", "", "sha1", "Hash format of --breach-db (sha1|ntlm)")
+	rootCmd.Flags().StringP("entropy", "", "system", "Entropy source: system, file:PATH or seed:TEXT (comma-separated sources are mixed)")
+	rootCmd.Flags().StringP("entropy-mix", "", "hash", "How multiple entropy sources are mixed (hash|xor)")
+	rootCmd.Flags().Float64P("health-entropy", "", defaultHealthEntropy, "Claimed min-entropy per source byte, used to derive health test cutoffs")
+	rootCmd.Flags().IntP("rct-cutoff", "", 0, "Repetition Count Test cutoff (0 = derive from --health-entropy)")
+	rootCmd.Flags().IntP("apt-cutoff", "", 0, "Adaptive Proportion Test cutoff per 512-byte window (0 = derive from --health-entropy)")
+	rootCmd.Flags().StringP("health-recovery", "", "retest", "After a health test failure: retest the source, or stay failed (retest|none)")
+	rootCmd.Flags().StringP("drbg", "", "none", "Expand the entropy source with an SP 800-90A DRBG (none|hmac-sha256|ctr-aes256)")
+	rootCmd.Flags().Uint64P("reseed-interval", "", defaultReseedInterval, "DRBG generate requests between reseeds")
+	rootCmd.Flags().BoolP("prediction-resistance", "", false, "Reseed the DRBG before every request")
+	rootCmd.Flags().StringP("personalization", "", "", "DRBG personalization string")
+	rootCmd.Flags().DurationP("timeout", "", 30*time.Second, "Timeout for batch generation; streams only stop at an explicit --timeout (0 = none)")
+
+	rootCmd.AddCommand(app.newCheckCommand())
+	rootCmd.AddCommand(app.newVerifyKeyCommand())
+	rootCmd.AddCommand(app.newVerifyMnemonicCommand())
+	rootCmd.AddCommand(app.newOTPCommand())
+	rootCmd.AddCommand(app.newDeriveCommand())
+	rootCmd.AddCommand(app.newBundleCommand())
+	rootCmd.AddCommand(app.newRenderCommand())
 
 	// Bind flags to viper for advanced configuration management
 	viper.BindPFlags(rootCmd.Flags())
@@ -605,23 +887,103 @@ Formats:
 
 // runCommand executes the main application logic with advanced error handling
 func (app *Application) runCommand(cmd *cobra.Command, args []string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("timeout"))
-	defer cancel()
+	// Interrupts stop generation cleanly, so partial output is flushed. Long
+	// streams are bounded by the interrupt alone unless --timeout is given.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	timeout := viper.GetDuration("timeout")
+	if timeout > 0 && (!viper.GetBool("stream") || cmd.Flags().Changed("timeout")) {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Select the entropy source before anything is generated
+	source, err := app.parseEntropySource()
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if c, ok := source.(io.Closer); ok {
+		defer c.Close()
+	}
+	app.generator = NewCryptoGeneratorWithEntropy(maxConcurrentGenerators, source)
 
 	// Parse and validate configuration
 	config, err := app.parseConfig()
+	if config != nil && config.BreachDB != nil {
+		defer config.BreachDB.Close()
+	}
 	if err != nil {
 		return fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	format, err := ParseOutputFormat(viper.GetString("format"))
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if viper.GetBool("show-entropy") {
+		app.showEntropy(config)
+	}
+
 	// Generate strings using the specified method
 	if viper.GetBool("stream") {
-		return app.generateStream(ctx, config)
+		return app.generateStream(ctx, config, format)
 	} else {
-		return app.generateBatch(ctx, config)
+		return app.generateBatch(ctx, config, format)
 	}
 }
 
+// parseEntropySource builds the entropy source selected by --entropy
+func (app *Application) parseEntropySource() (EntropySource, error) {
+	mix, err := ParseMixMethod(viper.GetString("entropy-mix"))
+	if err != nil {
+		return nil, err
+	}
+
+	health := DefaultHealthConfig()
+	health.RCTCutoff, health.APTCutoff = HealthCutoffs(viper.GetFloat64("health-entropy"), health.APTWindow)
+	health.RCTCutoff = cmp.Or(viper.GetInt("rct-cutoff"), health.RCTCutoff)
+	health.APTCutoff = cmp.Or(viper.GetInt("apt-cutoff"), health.APTCutoff)
+	health.Recovery, err = ParseRecoveryPolicy(viper.GetString("health-recovery"))
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := ParseEntropySource(viper.GetString("entropy"), mix, health)
+	if err != nil {
+		return nil, err
+	}
+	if source.Deterministic() {
+		fmt.Fprintln(os.Stderr, "Warning: seeded entropy is deterministic and must only be used for tests")
+	}
+
+	fail := func(err error) (EntropySource, error) {
+		if c, ok := source.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+
+	mechanism, err := ParseDRBGMechanism(viper.GetString("drbg"))
+	if err != nil {
+		return fail(err)
+	}
+	if mechanism == DRBGNone {
+		return source, nil
+	}
+	drbg, err := NewDRBGSource(source, DRBGConfig{
+		Mechanism:            mechanism,
+		ReseedInterval:       viper.GetUint64("reseed-interval"),
+		PredictionResistance: viper.GetBool("prediction-resistance"),
+		Personalization:      []byte(viper.GetString("personalization")),
+	})
+	if err != nil {
+		return fail(err)
+	}
+	return drbg, nil
+}
+
 // parseConfig parses and validates the application configuration
 func (app *Application) parseConfig() (*GeneratorConfig, error) {
 	genType, err := ParseGeneratorType(viper.GetString("type"))
@@ -629,17 +991,104 @@ func (app *Application) parseConfig() (*GeneratorConfig, error) {
 		return nil, err
 	}
 
-	charset := NewCharacterSet(viper.GetString("charset"))
-	if charset.Len() == 0 {
-		return nil, errors.New("charset cannot be empty")
+	charset, err := ParseCharset(viper.GetString("charset"))
+	if err != nil {
+		return nil, err
+	}
+	if viper.GetBool("exclude-ambiguous") {
+		charset = charset.Without(ambiguousChars)
+		if charset.Len() == 0 {
+			return nil, errors.New("charset is empty after removing ambiguous characters")
+		}
+	}
+
+	var pattern *Pattern
+	if genType == GeneratorPattern {
+		pattern, err = ParsePattern(viper.GetString("pattern"), charset)
+		if err != nil {
+			return nil, fmt.Errorf("parsing pattern: %w", err)
+		}
+	}
+
+	var wordlist *WordList
+	if path := viper.GetString("wordlist"); path != "" {
+		wordlist, err = LoadWordList(path)
+		if err != nil {
+			return nil, fmt.Errorf("loading wordlist: %w", err)
+		}
+	}
+
+	policy := PasswordPolicy{
+		MinUpper:  viper.GetInt("min-upper"),
+		MinLower:  viper.GetInt("min-lower"),
+		MinDigit:  viper.GetInt("min-digit"),
+		MinSymbol: viper.GetInt("min-symbol"),
+		Forbidden: viper.GetString("forbid"),
+		MaxRepeat: viper.GetInt("max-repeat"),
+	}
+
+	encoding, err := ParseTokenEncoding(viper.GetString("encoding"))
+	if err != nil {
+		return nil, err
+	}
+
+	checksum, err := ParseChecksumAlgorithm(viper.GetString("checksum"))
+	if err != nil {
+		return nil, err
+	}
+
+	onError, err := ParseErrorPolicy(viper.GetString("on-error"))
+	if err != nil {
+		return nil, err
+	}
+
+	var breachDB *BreachDB
+	if path := viper.GetString("breach-db"); path != "" {
+		hash, err := ParseBreachHash(viper.GetString("breach-hash"))
+		if err != nil {
+			return nil, err
+		}
+		breachDB, err = OpenBreachDB(path, hash)
+		if err != nil {
+			return nil, fmt.Errorf("opening breach corpus: %w", err)
+		}
+	}
+
+	if err := validateLayout(genType, viper.GetInt("groups"), viper.GetInt("group-size"), viper.GetString("separator")); err != nil {
+		return nil, err
+	}
+
+	// The hyphenated layout comes from --groups and --group-size; a length
+	// is only checked against it when given explicitly
+	length := viper.GetInt("length")
+	if genType == GeneratorHyphenated && !viper.IsSet("length") {
+		length = 0
 	}
 
 	config := &GeneratorConfig{
 		Type:         genType,
-		Length:       viper.GetInt("length"),
+		Length:       length,
 		Count:        viper.GetInt("count"),
 		Charset:      charset,
+		Policy:       policy,
+		Pattern:      pattern,
+		Bits:         viper.GetInt("bits"),
+		Encoding:     encoding,
+		Prefix:       viper.GetString("prefix"),
+		Checksum:     checksum,
+		Monotonic:    viper.GetBool("monotonic"),
+		Groups:       viper.GetInt("groups"),
+		GroupSize:    viper.GetInt("group-size"),
+		Wordlist:     wordlist,
+		Words:        viper.GetInt("words"),
+		Separator:    viper.GetString("separator"),
+		Capitalize:   viper.GetBool("capitalize"),
+		AppendDigit:  viper.GetBool("append-digit"),
+		MinEntropy:   viper.GetFloat64("min-entropy"),
+		BreachDB:     breachDB,
 		Parallel:     viper.GetBool("parallel"),
+		Unordered:    !viper.GetBool("ordered"),
+		OnError:      onError,
 		Workers:      viper.GetInt("workers"),
 		BatchSize:    maxBatchSize,
 		MemoryPool:   true,
@@ -650,76 +1099,176 @@ func (app *Application) parseConfig() (*GeneratorConfig, error) {
 }
 
 // generateBatch generates strings in batch mode
-func (app *Application) generateBatch(ctx context.Context, config *GeneratorConfig) error {
+func (app *Application) generateBatch(ctx context.Context, config *GeneratorConfig, format OutputFormat) error {
 	start := time.Now()
 
-	results, err := app.generator.GenerateBatch(ctx, config)
-	if err != nil {
-		return fmt.Errorf("generation failed: %w", err)
-	}
-
+	results, err := app.generator.GenerateBatchTimed(ctx, config)
 	duration := time.Since(start)
 
-	// Output results
+	// Output results, including those produced before skipped failures
+	out := NewItemWriter(os.Stdout, format)
+	template := config.itemTemplate()
 	for _, result := range results {
-		fmt.Println(result)
+		if err := out.WriteItem(newItem(template, result)); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("writing output: %w", err)
 	}
 
 	// Show statistics if requested
 	if viper.GetBool("stats") {
-		app.showStats(duration, config.Count)
+		app.showStats(config, format, duration, len(results))
 	}
 
+	if err != nil {
+		return fmt.Errorf("generation failed: %w", err)
+	}
 	return nil
 }
 
 // generateStream generates strings in streaming mode using Go 1.25 iterators
-func (app *Application) generateStream(ctx context.Context, config *GeneratorConfig) error {
+func (app *Application) generateStream(ctx context.Context, config *GeneratorConfig, format OutputFormat) error {
 	start := time.Now()
 	generated := 0
 
-	// Use the new iterator pattern from Go 1.25
-	for result, err := range app.generator.GenerateStream(ctx, config) {
+	out := NewItemWriter(os.Stdout, format)
+	template := config.itemTemplate()
+
+	// Use the new iterator pattern from Go 1.25. Errors reach this loop only
+	// when the error policy gives up, after everything produced so far.
+	var genErr error
+	for result, err := range app.generator.GenerateStreamTimed(ctx, config) {
 		if err != nil {
-			continue
+			genErr = err
+			break
 		}
 
-		fmt.Println(result)
+		if err := out.WriteItem(newItem(template, result)); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
 		generated++
 	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
 
 	duration := time.Since(start)
 
 	// Show statistics if requested
 	if viper.GetBool("stats") {
-		app.showStats(duration, generated)
+		app.showStats(config, format, duration, generated)
 	}
 
+	if genErr != nil {
+		return fmt.Errorf("generation failed: %w", genErr)
+	}
 	return nil
 }
 
-// showStats displays generation statistics
-func (app *Application) showStats(duration time.Duration, count int) {
+// showEntropy reports the entropy of each generated string
+func (app *Application) showEntropy(config *GeneratorConfig) {
+	bits := config.Entropy()
+	if bits < minEntropyBits {
+		fmt.Fprintf(os.Stderr, "Entropy: %.2f bits per string (below recommended %d bits)\n", bits, minEntropyBits)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Entropy: %.2f bits per string\n", bits)
+}
+
+// GenerationStats is the report printed by --stats
+type GenerationStats struct {
+	Generated       uint64     `json:"generated" yaml:"generated"`
+	Output          int        `json:"output" yaml:"output"`
+	Errors          uint64     `json:"errors" yaml:"errors"`
+	Skipped         uint64     `json:"skipped" yaml:"skipped"`
+	Retries         uint64     `json:"retries" yaml:"retries"`
+	ErrorPolicy     string     `json:"error_policy" yaml:"error_policy"`
+	DurationSeconds float64    `json:"duration_seconds" yaml:"duration_seconds"`
+	AverageSeconds  float64    `json:"average_seconds" yaml:"average_seconds"`
+	Throughput      float64    `json:"throughput_per_second" yaml:"throughput_per_second"`
+	EntropySource   string     `json:"entropy_source" yaml:"entropy_source"`
+	EntropyBytes    uint64     `json:"entropy_bytes" yaml:"entropy_bytes"`
+	EntropyErrors   uint64     `json:"entropy_errors" yaml:"entropy_errors"`
+	EntropyHealth   string     `json:"entropy_health" yaml:"entropy_health"`
+	EntropyBits     float64    `json:"entropy_bits_per_string" yaml:"entropy_bits_per_string"`
+	DRBG            *DRBGStats `json:"drbg,omitempty" yaml:"drbg,omitempty"`
+	Breached        *uint64    `json:"breached_rejected,omitempty" yaml:"breached_rejected,omitempty"`
+	WorkersBusy     int        `json:"workers_busy" yaml:"workers_busy"`
+	WorkersLimit    int        `json:"workers_limit" yaml:"workers_limit"`
+	HardwareAES     bool       `json:"hardware_aes" yaml:"hardware_aes"`
+	AVX2            bool       `json:"avx2" yaml:"avx2"`
+}
+
+// DRBGStats describes the DRBG in use
+type DRBGStats struct {
+	Reseeds              uint64 `json:"reseeds" yaml:"reseeds"`
+	ReseedInterval       uint64 `json:"reseed_interval" yaml:"reseed_interval"`
+	PredictionResistance bool   `json:"prediction_resistance" yaml:"prediction_resistance"`
+}
+
+// collectStats gathers the statistics of a run that output count strings
+func (app *Application) collectStats(config *GeneratorConfig, duration time.Duration, count int) *GenerationStats {
 	generated, errors, avgDuration := app.generator.Stats()
 	entropyGenerated, entropyErrors := app.generator.entropy.Stats()
 
-	fmt.Fprintf(os.Stderr, "\n--- Generation Statistics ---\n")
-	fmt.Fprintf(os.Stderr, "Total Generated: %d strings\n", generated)
-	fmt.Fprintf(os.Stderr, "Total Errors: %d\n", errors)
-	fmt.Fprintf(os.Stderr, "Batch Duration: %v\n", duration)
-	fmt.Fprintf(os.Stderr, "Average Duration: %v per string\n", avgDuration)
-	fmt.Fprintf(os.Stderr, "Throughput: %.2f strings/sec\n", float64(count)/duration.Seconds())
-	fmt.Fprintf(os.Stderr, "Entropy Generated: %d bytes\n", entropyGenerated)
-	fmt.Fprintf(os.Stderr, "Entropy Errors: %d\n", entropyErrors)
-	fmt.Fprintf(os.Stderr, "Worker Utilization: %d/%d\n", len(app.generator.workers), cap(app.generator.workers))
-
-	// CPU feature detection for optimization insights
-	if cpu.X86.HasAES {
-		fmt.Fprintf(os.Stderr, "Hardware AES: Available\n")
+	stats := &GenerationStats{
+		Generated:       generated,
+		Output:          count,
+		Errors:          errors,
+		Skipped:         app.generator.stats.skipped.Load(),
+		Retries:         app.generator.stats.retries.Load(),
+		ErrorPolicy:     config.OnError.String(),
+		DurationSeconds: duration.Seconds(),
+		AverageSeconds:  avgDuration.Seconds(),
+		Throughput:      float64(count) / duration.Seconds(),
+		EntropySource:   app.generator.entropy.String(),
+		EntropyBytes:    entropyGenerated,
+		EntropyErrors:   entropyErrors,
+		EntropyHealth:   "ok",
+		EntropyBits:     config.Entropy(),
+		WorkersBusy:     len(app.generator.workers),
+		WorkersLimit:    cap(app.generator.workers),
+		HardwareAES:     cpu.X86.HasAES,
+		AVX2:            cpu.X86.HasAVX2,
 	}
-	if cpu.X86.HasAVX2 {
-		fmt.Fprintf(os.Stderr, "AVX2 SIMD: Available\n")
+	if err := app.generator.entropy.Health(); err != nil {
+		stats.EntropyHealth = err.Error()
+	}
+	if drbg, ok := app.generator.entropy.(*drbgSource); ok {
+		stats.DRBG = &DRBGStats{
+			Reseeds:              drbg.Reseeds(),
+			ReseedInterval:       drbg.config.ReseedInterval,
+			PredictionResistance: drbg.config.PredictionResistance,
+		}
+	}
+	if config.BreachDB != nil {
+		breached := app.generator.stats.breached.Load()
+		stats.Breached = &breached
+	}
+	return stats
+}
+
+// showStats writes generation statistics to stderr as one machine-readable
+// object: YAML alongside YAML output, and JSON for every other format
+func (app *Application) showStats(config *GeneratorConfig, format OutputFormat, duration time.Duration, count int) {
+	writeStats(os.Stderr, app.collectStats(config, duration, count), format)
+}
+
+// writeStats writes stats as a {"stats": ...} object in the encoding that
+// suits format
+func writeStats(w io.Writer, stats *GenerationStats, format OutputFormat) error {
+	report := map[string]*GenerationStats{"stats": stats}
+	if format == OutputYAML {
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+		return enc.Close()
 	}
+	return json.NewEncoder(w).Encode(report)
 }
 
 // Utility functions
@@ -7,14 +7,29 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"gopkg.in/yaml.v3"
+	"rsc.io/qr"
 )
 
 // Test suite for advanced generator functionality
@@ -30,6 +45,22 @@ func TestGeneratorType(t *testing.T) {
 		{"hyphenated_short", "h", GeneratorHyphenated, false},
 		{"compact", "compact", GeneratorCompact, false},
 		{"compact_short", "c", GeneratorCompact, false},
+		{"passphrase", "passphrase", GeneratorPassphrase, false},
+		{"passphrase_short", "p", GeneratorPassphrase, false},
+		{"pattern", "pattern", GeneratorPattern, false},
+		{"pattern_short", "m", GeneratorPattern, false},
+		{"pronounceable", "pronounceable", GeneratorPronounceable, false},
+		{"pronounceable_short", "s", GeneratorPronounceable, false},
+		{"token", "token", GeneratorToken, false},
+		{"token_short", "k", GeneratorToken, false},
+		{"apikey", "apikey", GeneratorAPIKey, false},
+		{"apikey_short", "a", GeneratorAPIKey, false},
+		{"uuid4", "uuid4", GeneratorUUIDv4, false},
+		{"uuid_alias", "uuid", GeneratorUUIDv4, false},
+		{"uuid7", "uuid7", GeneratorUUIDv7, false},
+		{"ulid", "ulid", GeneratorULID, false},
+		{"mnemonic", "mnemonic", GeneratorMnemonic, false},
+		{"mnemonic_alias", "bip39", GeneratorMnemonic, false},
 		{"invalid", "invalid", 0, true},
 		{"empty", "", 0, true},
 	}
@@ -87,6 +118,66 @@ func TestCharacterSet(t *testing.T) {
 	})
 }
 
+func TestParseCharset(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{"preset_hex", "hex", "0123456789abcdef", false},
+		{"preset_case_insensitive", "Base32", "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567", false},
+		{"preset_crockford", "crockford", "0123456789ABCDEFGHJKMNPQRSTVWXYZ", false},
+		{"ranges", "a-f0-3_", "abcdef0123_", false},
+		{"literal_dash_edges", "-ab-", "-ab", false},
+		{"escaped_dash", `a\-c`, "a-c", false},
+		{"symbol_list", "!@#$%^&*()-_=+", "!@#$%^&*()-_=+", false},
+		{"cross_class_literal", "a-Z9-a", "a-Z9", false},
+		{"escaped_range_start", `\a-c`, "a-c", false},
+		{"raw_string", alphanumericChars, alphanumericChars, false},
+		{"reversed_range", "z-a", "", true},
+		{"empty", "", "", true},
+		{"trailing_escape", `ab\`, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cs, err := ParseCharset(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseCharset(%q) expected error, got nil", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCharset(%q) unexpected error: %v", tt.spec, err)
+			}
+			if cs.String() != tt.want {
+				t.Errorf("ParseCharset(%q) = %q, want %q", tt.spec, cs.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("presets_valid", func(t *testing.T) {
+		for _, name := range CharsetPresets() {
+			cs, err := ParseCharset(name)
+			if err != nil || cs.Len() == 0 {
+				t.Errorf("Preset %q is invalid: %v", name, err)
+			}
+		}
+	})
+
+	t.Run("exclude_ambiguous", func(t *testing.T) {
+		cs := NewCharacterSet(alphanumericChars).Without(ambiguousChars)
+		if strings.ContainsAny(cs.String(), ambiguousChars) {
+			t.Errorf("Charset %q still contains ambiguous characters", cs.String())
+		}
+		if cs.Len() != len(alphanumericChars)-5 {
+			t.Errorf("Charset length = %d, want %d", cs.Len(), len(alphanumericChars)-5)
+		}
+	})
+}
+
 func TestGeneratorConfig(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -137,6 +228,42 @@ func TestGeneratorConfig(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "policy_exceeds_length",
+			config: &GeneratorConfig{
+				Type:    GeneratorCompact,
+				Length:  4,
+				Count:   1,
+				Charset: NewCharacterSet(alphanumericChars),
+				Policy:  PasswordPolicy{MinUpper: 2, MinDigit: 3},
+				Workers: 1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "policy_class_missing",
+			config: &GeneratorConfig{
+				Type:    GeneratorCompact,
+				Length:  16,
+				Count:   1,
+				Charset: NewCharacterSet(alphanumericChars),
+				Policy:  PasswordPolicy{MinSymbol: 1},
+				Workers: 1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "policy_forbids_class",
+			config: &GeneratorConfig{
+				Type:    GeneratorCompact,
+				Length:  16,
+				Count:   1,
+				Charset: NewCharacterSet(alphanumericChars),
+				Policy:  PasswordPolicy{MinDigit: 1, Forbidden: digits},
+				Workers: 1,
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -214,8 +341,8 @@ func TestEntropySource(t *testing.T) {
 	})
 
 	t.Run("health_check", func(t *testing.T) {
-		if !es.Health() {
-			t.Error("EntropySource should be healthy")
+		if err := es.Health(); err != nil {
+			t.Errorf("EntropySource should be healthy: %v", err)
 		}
 	})
 
@@ -230,136 +357,2621 @@ func TestEntropySource(t *testing.T) {
 	})
 }
 
-func TestCryptoGenerator(t *testing.T) {
-	gen := NewCryptoGenerator(4)
-	ctx := context.Background()
+// failingSource is an EntropySource whose reads always fail
+type failingSource struct{}
+
+func (failingSource) String() string                    { return "failing" }
+func (failingSource) GenerateBytes(int) ([]byte, error) { return nil, errors.New("device unplugged") }
+func (failingSource) GenerateUint64() (uint64, error)   { return 0, errors.New("device unplugged") }
+func (failingSource) Health() error                     { return errors.New("device unplugged") }
+func (failingSource) Reset() error                      { return nil }
+func (failingSource) Stats() (uint64, uint64)           { return 0, 1 }
+func (failingSource) Deterministic() bool               { return false }
+
+func TestEntropySources(t *testing.T) {
+	t.Run("seeded_deterministic", func(t *testing.T) {
+		a, _ := NewSeededEntropySource([]byte("fixture")).GenerateBytes(64)
+		b, _ := NewSeededEntropySource([]byte("fixture")).GenerateBytes(64)
+		c, _ := NewSeededEntropySource([]byte("other")).GenerateBytes(64)
+		if !slices.Equal(a, b) {
+			t.Error("Seeded sources with the same seed differ")
+		}
+		if slices.Equal(a, c) {
+			t.Error("Seeded sources with different seeds agree")
+		}
 
-	config := &GeneratorConfig{
-		Type:         GeneratorCompact,
-		Length:       16,
-		Count:        1,
-		Charset:      NewCharacterSet(alphanumericChars),
-		Parallel:     false,
-		Workers:      1,
-		ConstantTime: true,
-	}
+		// Reads continue the stream rather than restarting it
+		src := NewSeededEntropySource([]byte("fixture"))
+		first, _ := src.GenerateBytes(32)
+		second, _ := src.GenerateBytes(32)
+		if !slices.Equal(append(first, second...), a) {
+			t.Error("Seeded source does not continue its stream across reads")
+		}
+	})
 
-	t.Run("single_generation", func(t *testing.T) {
-		result, err := gen.Generate(ctx, config)
+	t.Run("deterministic", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "seeded.bin")
+		os.WriteFile(path, make([]byte, 64), 0o600)
+		file, err := NewFileEntropySource(path)
 		if err != nil {
-			t.Fatalf("CryptoGenerator.Generate() error: %v", err)
+			t.Fatalf("NewFileEntropySource() error: %v", err)
+		}
+		defer file.(io.Closer).Close()
+
+		seeded := NewSeededEntropySource([]byte("fixture"))
+		tested, _ := NewHealthTestedSource(seeded, DefaultHealthConfig())
+		drbg, _ := NewDRBGSource(seeded, DRBGConfig{Mechanism: DRBGHMACSHA256, ReseedInterval: 1})
+		mixed, _ := NewMixingEntropySource(MixHash, seeded, NewSystemEntropySource())
+		for _, tt := range []struct {
+			source EntropySource
+			want   bool
+		}{
+			{NewSystemEntropySource(), false},
+			{file, false},
+			{seeded, true},
+			{tested, true},
+			{drbg, true},
+			{mixed, false},
+		} {
+			if got := tt.source.Deterministic(); got != tt.want {
+				t.Errorf("%s: Deterministic() = %t, want %t", tt.source, got, tt.want)
+			}
 		}
+	})
 
-		if len(result) != config.Length {
-			t.Errorf("Generated string length = %d, want %d", len(result), config.Length)
+	t.Run("seeded_generator", func(t *testing.T) {
+		config := &GeneratorConfig{Type: GeneratorCompact, Length: 16, Charset: NewCharacterSet(alphanumericChars), Count: 5, Workers: 1}
+		var runs [2][]string
+		for i := range runs {
+			gen := NewCryptoGeneratorWithEntropy(4, NewSeededEntropySource([]byte("fixture")))
+			results, err := gen.GenerateBatch(context.Background(), config)
+			if err != nil {
+				t.Fatalf("GenerateBatch() error: %v", err)
+			}
+			runs[i] = results
+		}
+		if !slices.Equal(runs[0], runs[1]) {
+			t.Errorf("Seeded generators differ: %v vs %v", runs[0], runs[1])
 		}
+	})
 
-		// Verify all characters are from charset
-		for _, char := range result {
-			charset := config.Charset
-			valid := false
-			for i := 0; i < charset.Len(); i++ {
-				if byte(char) == charset.chars[i] {
-					valid = true
-					break
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "entropy.bin")
+		if err := os.WriteFile(path, []byte("0123456789abcdef"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		src, err := NewFileEntropySource(path)
+		if err != nil {
+			t.Fatalf("NewFileEntropySource() error: %v", err)
+		}
+		defer src.(io.Closer).Close()
+
+		got, err := src.GenerateBytes(10)
+		if err != nil || string(got) != "0123456789" {
+			t.Fatalf("GenerateBytes() = %q, %v", got, err)
+		}
+		if _, err := src.GenerateBytes(10); err == nil {
+			t.Error("GenerateBytes() expected error at end of file")
+		}
+		if src.Health() == nil {
+			t.Error("File source should be unhealthy after a failed read")
+		}
+		if _, errs := src.Stats(); errs != 1 {
+			t.Errorf("Stats() errors = %d, want 1", errs)
+		}
+	})
+
+	t.Run("mix_xor", func(t *testing.T) {
+		a, _ := NewSeededEntropySource([]byte("a")).GenerateBytes(48)
+		b, _ := NewSeededEntropySource([]byte("b")).GenerateBytes(48)
+		mix, err := NewMixingEntropySource(MixXOR, NewSeededEntropySource([]byte("a")), NewSeededEntropySource([]byte("b")))
+		if err != nil {
+			t.Fatalf("NewMixingEntropySource() error: %v", err)
+		}
+		got, err := mix.GenerateBytes(48)
+		if err != nil {
+			t.Fatalf("GenerateBytes() error: %v", err)
+		}
+		for i := range got {
+			if got[i] != a[i]^b[i] {
+				t.Fatalf("XOR mix byte %d = %02x, want %02x", i, got[i], a[i]^b[i])
+			}
+		}
+	})
+
+	t.Run("mix_hash", func(t *testing.T) {
+		newMix := func() EntropySource {
+			mix, err := NewMixingEntropySource(MixHash, NewSeededEntropySource([]byte("a")), NewSeededEntropySource([]byte("b")))
+			if err != nil {
+				t.Fatalf("NewMixingEntropySource() error: %v", err)
+			}
+			return mix
+		}
+		x, _ := newMix().GenerateBytes(50)
+		y, _ := newMix().GenerateBytes(50)
+		if len(x) != 50 || !slices.Equal(x, y) {
+			t.Error("Hash mix is not deterministic over deterministic inputs")
+		}
+	})
+
+	t.Run("mix_failure", func(t *testing.T) {
+		mix, _ := NewMixingEntropySource(MixHash, NewEntropySource(), failingSource{})
+		if _, err := mix.GenerateBytes(16); err == nil {
+			t.Error("Mixing source should fail when an input fails")
+		}
+		if mix.Health() == nil {
+			t.Error("Mixing source should be unhealthy after an input failure")
+		}
+
+		gen := NewCryptoGeneratorWithEntropy(1, failingSource{})
+		config := &GeneratorConfig{Type: GeneratorToken, Bits: 128, Count: 1, Workers: 1}
+		if _, err := gen.Generate(context.Background(), config); err == nil {
+			t.Error("Generate() should fail when the entropy source fails")
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		for spec, want := range map[string]string{
+			"system":          "system",
+			"seed:x":          "seeded",
+			"system, seed:x":  "mix-hash(system,seeded)",
+			"file:/dev/zero":  "file:/dev/zero",
+			"device:/dev/nul": "",
+			"seed:":           "",
+			"quantum":         "",
+		} {
+			src, err := ParseEntropySource(spec, MixHash, DefaultHealthConfig())
+			if want == "" {
+				if err == nil {
+					t.Errorf("ParseEntropySource(%q) expected error", spec)
 				}
+				continue
 			}
-			if !valid {
-				t.Errorf("Generated string contains invalid char: %c", char)
+			if err != nil {
+				t.Errorf("ParseEntropySource(%q) error: %v", spec, err)
+				continue
+			}
+			if src.String() != want {
+				t.Errorf("ParseEntropySource(%q) = %s, want %s", spec, src, want)
+			}
+			if c, ok := src.(io.Closer); ok {
+				c.Close()
 			}
 		}
 	})
+}
 
-	t.Run("batch_generation", func(t *testing.T) {
-		config.Count = 5
-		results, err := gen.GenerateBatch(ctx, config)
-		if err != nil {
-			t.Fatalf("CryptoGenerator.GenerateBatch() error: %v", err)
+// scriptedSource replays fixed byte sequences, one per read
+type scriptedSource struct {
+	*sourceCore
+}
+
+// newScriptedSource creates a source that returns reads in order and then
+// fails
+func newScriptedSource(reads ...[]byte) *scriptedSource {
+	var mu sync.Mutex
+	return &scriptedSource{newSourceCore("scripted", func(b []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(reads) == 0 {
+			return io.EOF
 		}
+		copy(b, reads[0])
+		reads = reads[1:]
+		return nil
+	})}
+}
 
-		if len(results) != config.Count {
-			t.Errorf("Generated batch size = %d, want %d", len(results), config.Count)
+func TestHealthTests(t *testing.T) {
+	config := DefaultHealthConfig()
+	good := func(n int) []byte {
+		b, _ := NewSeededEntropySource([]byte("healthy")).GenerateBytes(n)
+		return b
+	}
+
+	t.Run("cutoffs", func(t *testing.T) {
+		for _, tt := range []struct {
+			h        float64
+			rct, apt int
+		}{
+			{8, 6, 19},
+			{1, 41, 336},
+		} {
+			rct, apt := HealthCutoffs(tt.h, defaultAPTWindow)
+			if rct != tt.rct || apt != tt.apt {
+				t.Errorf("HealthCutoffs(%g) = %d, %d, want %d, %d", tt.h, rct, apt, tt.rct, tt.apt)
+			}
 		}
+	})
 
-		// Verify uniqueness (highly probable for crypto random)
-		unique := make(map[string]bool)
-		for _, result := range results {
-			if unique[result] {
-				t.Errorf("Duplicate string in batch: %s", result)
+	t.Run("repetition_count", func(t *testing.T) {
+		ht := healthTester{config: config}
+		stuck := append(good(100), bytes.Repeat([]byte{7}, config.RCTCutoff)...)
+		if err := ht.feed(stuck); !errors.Is(err, ErrRepetitionCount) {
+			t.Errorf("feed() error = %v, want ErrRepetitionCount", err)
+		}
+
+		ht.reset()
+		if err := ht.feed(bytes.Repeat([]byte{7}, config.RCTCutoff-1)); err != nil {
+			t.Errorf("feed() error below cutoff: %v", err)
+		}
+	})
+
+	t.Run("adaptive_proportion", func(t *testing.T) {
+		// Alternate bytes so the RCT never fires but one value dominates
+		biased := make([]byte, config.APTWindow)
+		for i := range biased {
+			biased[i] = byte(i%2) * byte(i)
+		}
+		ht := healthTester{config: config}
+		if err := ht.feed(biased); !errors.Is(err, ErrAdaptiveProportion) {
+			t.Errorf("feed() error = %v, want ErrAdaptiveProportion", err)
+		}
+	})
+
+	t.Run("healthy_stream", func(t *testing.T) {
+		src, _ := NewHealthTestedSource(NewSeededEntropySource([]byte("healthy")), config)
+		for range 100 {
+			if _, err := src.GenerateBytes(4096); err != nil {
+				t.Fatalf("GenerateBytes() error: %v", err)
 			}
-			unique[result] = true
+		}
+		if err := src.Health(); err != nil {
+			t.Errorf("Health() = %v", err)
 		}
 	})
 
-	t.Run("parallel_generation", func(t *testing.T) {
-		config.Count = 10
-		config.Parallel = true
-		config.Workers = 4
+	t.Run("startup_failure", func(t *testing.T) {
+		src, _ := NewHealthTestedSource(newScriptedSource(make([]byte, startupSamples)), config)
+		if _, err := src.GenerateBytes(16); err == nil {
+			t.Fatal("GenerateBytes() should fail the startup test on a stuck source")
+		}
+		if err := src.Health(); !errors.Is(err, ErrRepetitionCount) {
+			t.Errorf("Health() = %v, want ErrRepetitionCount", err)
+		}
+	})
 
-		results, err := gen.GenerateBatch(ctx, config)
+	t.Run("recovery_retest", func(t *testing.T) {
+		stuck := append(good(8), make([]byte, 8)...)
+		raw := newScriptedSource(good(startupSamples), stuck, good(startupSamples), good(16))
+		src, _ := NewHealthTestedSource(raw, config)
+
+		if _, err := src.GenerateBytes(16); !errors.Is(err, ErrRepetitionCount) {
+			t.Fatalf("GenerateBytes() error = %v, want ErrRepetitionCount", err)
+		}
+		if src.Health() == nil {
+			t.Error("Health() should report the failure")
+		}
+		if _, err := src.GenerateBytes(16); err != nil {
+			t.Fatalf("GenerateBytes() after recovery error: %v", err)
+		}
+		if err := src.Health(); err != nil {
+			t.Errorf("Health() after recovery = %v", err)
+		}
+		if _, failures := src.Stats(); failures != 1 {
+			t.Errorf("Stats() failures = %d, want 1", failures)
+		}
+	})
+
+	t.Run("recovery_none", func(t *testing.T) {
+		latched := config
+		latched.Recovery = RecoveryNone
+		stuck := make([]byte, 16)
+		raw := newScriptedSource(good(startupSamples), stuck, good(startupSamples), good(16))
+		src, _ := NewHealthTestedSource(raw, latched)
+
+		if _, err := src.GenerateBytes(16); err == nil {
+			t.Fatal("GenerateBytes() should fail on a stuck read")
+		}
+		if _, err := src.GenerateBytes(16); err == nil {
+			t.Fatal("GenerateBytes() should stay failed without recovery")
+		}
+		if err := src.Reset(); err != nil {
+			t.Fatalf("Reset() error: %v", err)
+		}
+		if _, err := src.GenerateBytes(16); err != nil {
+			t.Errorf("GenerateBytes() after Reset error: %v", err)
+		}
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		if _, err := NewHealthTestedSource(NewSystemEntropySource(), HealthConfig{RCTCutoff: 1, APTWindow: 512, APTCutoff: 600}); err == nil {
+			t.Error("NewHealthTestedSource() expected error for invalid cutoffs")
+		}
+		if _, err := ParseRecoveryPolicy("sometimes"); err == nil {
+			t.Error("ParseRecoveryPolicy() expected error")
+		}
+	})
+}
+
+func TestDRBG(t *testing.T) {
+	unhex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
 		if err != nil {
-			t.Fatalf("Parallel generation error: %v", err)
+			t.Fatal(err)
+		}
+		return b
+	}
+	seq := func(from, to byte) []byte {
+		var b []byte
+		for v := from; v <= to; v++ {
+			b = append(b, v)
 		}
+		return b
+	}
 
-		if len(results) != config.Count {
-			t.Errorf("Parallel batch size = %d, want %d", len(results), config.Count)
+	t.Run("hmac_sha256_kat", func(t *testing.T) {
+		// NIST CAVP HMAC_DRBG.rsp, [SHA-256] [PredictionResistance = False]
+		// [EntropyInputLen = 256] [NonceLen = 128] [PersonalizationStringLen = 0]
+		// [AdditionalInputLen = 0], COUNT = 0
+		var d hmacDRBG
+		d.instantiate(
+			unhex("ca851911349384bffe89de1cbdc46e6831e44d34a4fb935ee285dd14b71a7488"),
+			unhex("659ba96c601dc69fc902940805ec0ca8"),
+			nil,
+		)
+		out := make([]byte, 128)
+		d.generate(out, nil)
+		d.generate(out, nil)
+		want := "e528e9abf2dece54d47c7e75e5fe302149f817ea9fb4bee6f4199697d04d5b89" +
+			"d54fbb978a15b5c443c9ec21036d2460b6f73ebad0dc2aba6e624abf07745bc1" +
+			"07694bb7547bb0995f70de25d6b29e2d3011bb19d27676c07162c8b5ccde0668" +
+			"961df86803482cb37ed6d5c0bb8d50cf1f50d476aa0458bdaba806f48be9dcb8"
+		if got := hex.EncodeToString(out); got != want {
+			t.Errorf("generate() = %s, want %s", got, want)
 		}
 	})
 
-	t.Run("stream_generation", func(t *testing.T) {
-		config.Count = 3
-		results := make([]string, 0, config.Count)
+	t.Run("ctr_aes256_kat", func(t *testing.T) {
+		// CTR_DRBG AES-256 without derivation function, with reseed and
+		// additional input; the known answer used by the Go FIPS module self-test
+		var d ctrDRBG
+		d.instantiate(seq(0x01, 0x30), nil, nil)
+		d.reseed(seq(0x31, 0x60), seq(0x61, 0x90))
+		out := make([]byte, 32)
+		d.generate(out, seq(0x61, 0x90))
+		want := "6e6e479d24f86a3b7787a8f8186d985a53bebeeddeab9228f0f4ac6e10bf0193"
+		if got := hex.EncodeToString(out); got != want {
+			t.Errorf("generate() = %s, want %s", got, want)
+		}
+	})
 
-		for result, err := range gen.GenerateStream(ctx, config) {
-			if err != nil {
-				t.Fatalf("Stream generation error: %v", err)
+	t.Run("reseed_interval", func(t *testing.T) {
+		src, err := NewDRBGSource(NewSeededEntropySource([]byte("drbg")), DRBGConfig{
+			Mechanism:      DRBGHMACSHA256,
+			ReseedInterval: 4,
+		})
+		if err != nil {
+			t.Fatalf("NewDRBGSource() error: %v", err)
+		}
+		for range 10 {
+			if _, err := src.GenerateBytes(32); err != nil {
+				t.Fatalf("GenerateBytes() error: %v", err)
 			}
-			results = append(results, result)
 		}
+		if got := src.(*drbgSource).Reseeds(); got != 2 {
+			t.Errorf("Reseeds() = %d, want 2", got)
+		}
+		if generated, _ := src.Stats(); generated != 320 {
+			t.Errorf("Stats() generated = %d, want 320", generated)
+		}
+	})
 
-		if len(results) != config.Count {
-			t.Errorf("Stream results count = %d, want %d", len(results), config.Count)
+	t.Run("prediction_resistance", func(t *testing.T) {
+		src, _ := NewDRBGSource(NewSeededEntropySource([]byte("drbg")), DRBGConfig{
+			Mechanism:            DRBGCTRAES256,
+			ReseedInterval:       defaultReseedInterval,
+			PredictionResistance: true,
+		})
+		for range 5 {
+			src.GenerateBytes(16)
+		}
+		// Instantiation consumes the first request's seed
+		if got := src.(*drbgSource).Reseeds(); got != 4 {
+			t.Errorf("Reseeds() = %d, want 4", got)
 		}
 	})
 
-	t.Run("context_cancellation", func(t *testing.T) {
-		ctx, cancel := context.WithCancel(context.Background())
-		cancel() // Cancel immediately
+	t.Run("deterministic_and_personalized", func(t *testing.T) {
+		for _, mech := range []DRBGMechanism{DRBGHMACSHA256, DRBGCTRAES256} {
+			generate := func(personalization string) []byte {
+				src, err := NewDRBGSource(NewSeededEntropySource([]byte("drbg")), DRBGConfig{
+					Mechanism:       mech,
+					ReseedInterval:  defaultReseedInterval,
+					Personalization: []byte(personalization),
+				})
+				if err != nil {
+					t.Fatalf("NewDRBGSource(%v) error: %v", mech, err)
+				}
+				b, err := src.GenerateBytes(64)
+				if err != nil {
+					t.Fatalf("GenerateBytes() error: %v", err)
+				}
+				return b
+			}
 
-		_, err := gen.Generate(ctx, config)
-		if err == nil || err != context.Canceled {
-			t.Errorf("Expected context.Canceled, got: %v", err)
+			if !bytes.Equal(generate("a"), generate("a")) {
+				t.Errorf("%v: same seed and personalization should give the same output", mech)
+			}
+			if bytes.Equal(generate("a"), generate("b")) {
+				t.Errorf("%v: personalization should change the output", mech)
+			}
+		}
+	})
+
+	t.Run("large_request", func(t *testing.T) {
+		src, _ := NewDRBGSource(NewSeededEntropySource([]byte("drbg")), DRBGConfig{
+			Mechanism:      DRBGHMACSHA256,
+			ReseedInterval: 1,
+		})
+		b, err := src.GenerateBytes(drbgMaxRequest*2 + 1)
+		if err != nil || len(b) != drbgMaxRequest*2+1 {
+			t.Fatalf("GenerateBytes() = %d bytes, %v", len(b), err)
+		}
+		if got := src.(*drbgSource).Reseeds(); got != 2 {
+			t.Errorf("Reseeds() = %d, want 2 (one per request after the first)", got)
+		}
+		if src.String() != "hmac-sha256-drbg(seeded)" {
+			t.Errorf("String() = %q", src.String())
+		}
+	})
+
+	t.Run("seed_failure", func(t *testing.T) {
+		src, _ := NewDRBGSource(failingSource{}, DRBGConfig{Mechanism: DRBGCTRAES256, ReseedInterval: 1})
+		if _, err := src.GenerateBytes(16); err == nil {
+			t.Fatal("GenerateBytes() should fail when the seed source fails")
+		}
+		if src.Health() == nil {
+			t.Error("Health() should report the seeding failure")
+		}
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		for _, config := range []DRBGConfig{
+			{Mechanism: DRBGNone, ReseedInterval: 1},
+			{Mechanism: DRBGHMACSHA256},
+			{Mechanism: DRBGHMACSHA256, ReseedInterval: drbgMaxReseedInterval + 1},
+			{Mechanism: DRBGCTRAES256, ReseedInterval: 1, Personalization: make([]byte, ctrDRBGSeedSize+1)},
+		} {
+			if err := config.Validate(); err == nil {
+				t.Errorf("Validate(%+v) expected error", config)
+			}
+		}
+		if m, err := ParseDRBGMechanism("CTR"); err != nil || m != DRBGCTRAES256 {
+			t.Errorf("ParseDRBGMechanism(CTR) = %v, %v", m, err)
+		}
+		if _, err := ParseDRBGMechanism("dual-ec"); err == nil {
+			t.Error("ParseDRBGMechanism() expected error")
 		}
 	})
 }
 
-func TestHyphenatedGeneration(t *testing.T) {
-	gen := NewCryptoGenerator(4)
+func TestGenerateStream(t *testing.T) {
 	ctx := context.Background()
-
-	config := &GeneratorConfig{
-		Type:    GeneratorHyphenated,
-		Length:  18, // Set a valid length
-		Count:   1,
-		Charset: NewCharacterSet(alphanumericChars),
-		Workers: 1,
+	newConfig := func(count int, ordered bool) *GeneratorConfig {
+		return &GeneratorConfig{
+			Type:      GeneratorCompact,
+			Length:    12,
+			Count:     count,
+			Charset:   NewCharacterSet(alphanumericChars),
+			Parallel:  true,
+			Unordered: !ordered,
+			Workers:   4,
+		}
 	}
 
-	result, err := gen.Generate(ctx, config)
-	if err != nil {
-		t.Fatalf("Hyphenated generation error: %v", err)
-	}
+	t.Run("beyond_batch_limit", func(t *testing.T) {
+		for _, ordered := range []bool{true, false} {
+			gen := NewCryptoGenerator(maxConcurrentGenerators)
+			config := newConfig(maxBatchSize*5, ordered)
 
-	parts := strings.Split(result, "-")
-	if len(parts) != 3 {
-		t.Errorf("Hyphenated string parts = %d, want 3", len(parts))
+			unique := make(map[string]bool, config.Count)
+			for result, err := range gen.GenerateStream(ctx, config) {
+				if err != nil {
+					t.Fatalf("GenerateStream(ordered=%t) error: %v", ordered, err)
+				}
+				unique[result] = true
+			}
+			if len(unique) != config.Count {
+				t.Errorf("GenerateStream(ordered=%t) = %d unique strings, want %d", ordered, len(unique), config.Count)
+			}
+
+			if _, err := gen.GenerateBatch(ctx, config); err == nil {
+				t.Error("GenerateBatch() should keep the batch limit")
+			}
+		}
+	})
+
+	t.Run("generation_index", func(t *testing.T) {
+		for _, ordered := range []bool{true, false} {
+			gen := NewCryptoGenerator(maxConcurrentGenerators)
+			config := newConfig(maxBatchSize, ordered)
+
+			var indices []int
+			for g, err := range gen.GenerateStreamTimed(ctx, config) {
+				if err != nil {
+					t.Fatalf("GenerateStreamTimed(ordered=%t) error: %v", ordered, err)
+				}
+				indices = append(indices, g.Index)
+			}
+			if ordered && !slices.IsSorted(indices) {
+				t.Error("ordered stream indices are not in output order")
+			}
+			slices.Sort(indices)
+			for i, index := range indices {
+				if index != i {
+					t.Fatalf("GenerateStreamTimed(ordered=%t) indices are not a permutation of 0-%d", ordered, config.Count-1)
+				}
+			}
+		}
+	})
+
+	t.Run("backpressure", func(t *testing.T) {
+		gen := NewCryptoGenerator(maxConcurrentGenerators)
+		config := newConfig(1_000_000, true)
+		before := runtime.NumGoroutine()
+
+		consumed := 0
+		for _, err := range gen.GenerateStream(ctx, config) {
+			if err != nil {
+				t.Fatalf("GenerateStream() error: %v", err)
+			}
+			if consumed++; consumed == 10 {
+				time.Sleep(10 * time.Millisecond) // Let the workers fill the window
+				break
+			}
+		}
+
+		window := uint64(config.Workers*streamWindowPerWorker + config.Workers)
+		if generated, _, _ := gen.Stats(); generated > uint64(consumed)+window {
+			t.Errorf("generated %d strings for %d consumed, want at most the window of %d ahead", generated, consumed, window)
+		}
+		if after := runtime.NumGoroutine(); after > before {
+			t.Errorf("goroutines after early stop = %d, want at most %d", after, before)
+		}
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		gen := NewCryptoGenerator(maxConcurrentGenerators)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var errs int
+		for _, err := range gen.GenerateStream(ctx, newConfig(1_000_000, false)) {
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("GenerateStream() error = %v, want context.Canceled", err)
+				}
+				errs++
+				continue
+			}
+			cancel()
+		}
+		if errs != 1 {
+			t.Errorf("GenerateStream() reported cancellation %d times, want once", errs)
+		}
+	})
+
+	t.Run("invalid_config", func(t *testing.T) {
+		gen := NewCryptoGenerator(maxConcurrentGenerators)
+		for _, err := range gen.GenerateStream(ctx, newConfig(0, true)) {
+			if err == nil {
+				t.Error("GenerateStream() expected error for zero count")
+			}
+		}
+	})
+}
+
+// flakySource fails every nth read of the system source without latching
+type flakySource struct {
+	EntropySource
+	n     uint64
+	reads atomic.Uint64
+}
+
+func newFlakySource(n uint64) *flakySource {
+	return &flakySource{EntropySource: NewSystemEntropySource(), n: n}
+}
+
+func (fs *flakySource) GenerateBytes(n int) ([]byte, error) {
+	if fs.reads.Add(1)%fs.n == 0 {
+		return nil, errors.New("flaky read")
+	}
+	return fs.EntropySource.GenerateBytes(n)
+}
+
+func TestErrorPolicy(t *testing.T) {
+	ctx := context.Background()
+	newConfig := func(policy string, parallel bool) *GeneratorConfig {
+		onError, err := ParseErrorPolicy(policy)
+		if err != nil {
+			t.Fatalf("ParseErrorPolicy(%q) error: %v", policy, err)
+		}
+		return &GeneratorConfig{
+			Type:     GeneratorCompact,
+			Length:   12,
+			Count:    10,
+			Charset:  NewCharacterSet(alphanumericChars),
+			Parallel: parallel,
+			Workers:  4,
+			OnError:  onError,
+		}
+	}
+
+	t.Run("parse", func(t *testing.T) {
+		for _, tt := range []struct {
+			input string
+			want  ErrorPolicy
+		}{
+			{"fail", ErrorPolicy{Action: ErrorFail}},
+			{"SKIP", ErrorPolicy{Action: ErrorSkip}},
+			{"retry=3", ErrorPolicy{Action: ErrorRetry, Retries: 3}},
+		} {
+			got, err := ParseErrorPolicy(tt.input)
+			if err != nil || got != tt.want {
+				t.Errorf("ParseErrorPolicy(%q) = %v, %v, want %v", tt.input, got, err, tt.want)
+			}
+			if got.String() != strings.ToLower(tt.input) {
+				t.Errorf("String() = %q, want %q", got.String(), strings.ToLower(tt.input))
+			}
+		}
+		for _, input := range []string{"retry", "retry=0", "retry=x", "skip=1", "ignore"} {
+			if _, err := ParseErrorPolicy(input); err == nil {
+				t.Errorf("ParseErrorPolicy(%q) expected error", input)
+			}
+		}
+	})
+
+	t.Run("batch_fail", func(t *testing.T) {
+		gen := NewCryptoGeneratorWithEntropy(maxConcurrentGenerators, newFlakySource(2))
+		results, err := gen.GenerateBatch(ctx, newConfig("fail", false))
+		var short *ShortCountError
+		if !errors.As(err, &short) || results != nil {
+			t.Fatalf("GenerateBatch() = %d results, %v, want *ShortCountError", len(results), err)
+		}
+		if short.Requested != 10 || short.Err == nil {
+			t.Errorf("ShortCountError = %+v", short)
+		}
+	})
+
+	t.Run("batch_skip", func(t *testing.T) {
+		for _, parallel := range []bool{false, true} {
+			gen := NewCryptoGeneratorWithEntropy(maxConcurrentGenerators, newFlakySource(2))
+			results, err := gen.GenerateBatch(ctx, newConfig("skip", parallel))
+			var short *ShortCountError
+			if !errors.As(err, &short) {
+				t.Fatalf("GenerateBatch(parallel=%t) error = %v, want *ShortCountError", parallel, err)
+			}
+			if len(results) != 5 || short.Produced != 5 || short.Skipped != 5 {
+				t.Errorf("GenerateBatch(parallel=%t) = %d results, %+v, want 5 produced and 5 skipped", parallel, len(results), short)
+			}
+			if slices.Contains(results, "") {
+				t.Error("GenerateBatch() returned an empty string for a skipped failure")
+			}
+			if skipped := gen.stats.skipped.Load(); skipped != 5 {
+				t.Errorf("skipped = %d, want 5", skipped)
+			}
+		}
+	})
+
+	t.Run("batch_retry", func(t *testing.T) {
+		gen := NewCryptoGeneratorWithEntropy(maxConcurrentGenerators, newFlakySource(2))
+		results, err := gen.GenerateBatch(ctx, newConfig("retry=1", false))
+		if err != nil || len(results) != 10 {
+			t.Fatalf("GenerateBatch() = %d results, %v", len(results), err)
+		}
+		// Every read after the first retried one lands on a failing call
+		if retries := gen.stats.retries.Load(); retries != 9 {
+			t.Errorf("retries = %d, want 9", retries)
+		}
+
+		// A source that never recovers exhausts the retries
+		gen = NewCryptoGeneratorWithEntropy(maxConcurrentGenerators, newFlakySource(1))
+		if _, err := gen.GenerateBatch(ctx, newConfig("retry=2", false)); err == nil {
+			t.Error("GenerateBatch() expected error after exhausting retries")
+		}
+		if retries := gen.stats.retries.Load(); retries != 2 {
+			t.Errorf("retries = %d, want 2", retries)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		for _, tt := range []struct {
+			policy            string
+			parallel          bool
+			produced, skipped int
+		}{
+			{"fail", false, 1, 0},
+			{"skip", false, 5, 5},
+			{"skip", true, 5, 5},
+			{"retry=1", true, 10, 0},
+		} {
+			gen := NewCryptoGeneratorWithEntropy(maxConcurrentGenerators, newFlakySource(2))
+			var produced int
+			var last error
+			for _, err := range gen.GenerateStream(ctx, newConfig(tt.policy, tt.parallel)) {
+				if err != nil {
+					last = err
+					continue
+				}
+				produced++
+			}
+
+			if produced != tt.produced {
+				t.Errorf("%s (parallel=%t): produced %d, want %d", tt.policy, tt.parallel, produced, tt.produced)
+			}
+			var short *ShortCountError
+			if tt.produced == 10 {
+				if last != nil {
+					t.Errorf("%s: unexpected error %v", tt.policy, last)
+				}
+			} else if !errors.As(last, &short) || short.Produced != tt.produced || short.Skipped != tt.skipped {
+				t.Errorf("%s (parallel=%t): final error = %v, want *ShortCountError with %d produced and %d skipped", tt.policy, tt.parallel, last, tt.produced, tt.skipped)
+			}
+		}
+	})
+}
+
+func TestOutputFormats(t *testing.T) {
+	config := &GeneratorConfig{Type: GeneratorCompact, Length: 8, Charset: NewCharacterSet(alphanumericChars)}
+	template := config.itemTemplate()
+	at := time.Date(2025, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	items := []OutputItem{
+		newItem(template, GeneratedString{Value: "abcDEF12", GeneratedAt: at}),
+		newItem(template, GeneratedString{Value: `a,b"c:d`, Index: 1, GeneratedAt: at.Add(time.Millisecond)}),
+	}
+
+	write := func(format OutputFormat, items []OutputItem) string {
+		var buf bytes.Buffer
+		w := NewItemWriter(&buf, format)
+		for _, item := range items {
+			if err := w.WriteItem(item); err != nil {
+				t.Fatalf("WriteItem(%v) error: %v", format, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close(%v) error: %v", format, err)
+		}
+		return buf.String()
+	}
+	check := func(format OutputFormat, got []OutputItem) {
+		t.Helper()
+		if len(got) != len(items) {
+			t.Fatalf("%v: decoded %d items, want %d", format, len(got), len(items))
+		}
+		for i, item := range got {
+			want := items[i]
+			if item.Index != want.Index || item.Value != want.Value || item.Type != "compact" || item.Length != want.Length ||
+				item.Charset != "alnum" || math.Abs(item.EntropyBits-want.EntropyBits) > 0.01 || !item.GeneratedAt.Equal(want.GeneratedAt.Truncate(0)) {
+				t.Errorf("%v: item %d = %+v, want %+v", format, i, item, want)
+			}
+		}
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		if got := write(OutputPlain, items); got != "abcDEF12\na,b\"c:d\n" {
+			t.Errorf("plain output = %q", got)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var got []OutputItem
+		if err := json.Unmarshal([]byte(write(OutputJSON, items)), &got); err != nil {
+			t.Fatalf("json output does not parse: %v", err)
+		}
+		check(OutputJSON, got)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		var got []OutputItem
+		for line := range strings.Lines(write(OutputNDJSON, items)) {
+			var item OutputItem
+			if err := json.Unmarshal([]byte(line), &item); err != nil {
+				t.Fatalf("ndjson line %q does not parse: %v", line, err)
+			}
+			got = append(got, item)
+		}
+		check(OutputNDJSON, got)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		records, err := csv.NewReader(strings.NewReader(write(OutputCSV, items))).ReadAll()
+		if err != nil {
+			t.Fatalf("csv output does not parse: %v", err)
+		}
+		if !slices.Equal(records[0], csvHeader) {
+			t.Errorf("csv header = %v", records[0])
+		}
+		var got []OutputItem
+		for _, r := range records[1:] {
+			index, _ := strconv.Atoi(r[0])
+			length, _ := strconv.Atoi(r[3])
+			bits, _ := strconv.ParseFloat(r[5], 64)
+			at, _ := time.Parse(time.RFC3339Nano, r[6])
+			got = append(got, OutputItem{Index: index, Value: r[1], Type: r[2], Length: length, Charset: r[4], EntropyBits: bits, GeneratedAt: at})
+		}
+		check(OutputCSV, got)
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		var got []OutputItem
+		if err := yaml.Unmarshal([]byte(write(OutputYAML, items)), &got); err != nil {
+			t.Fatalf("yaml output does not parse: %v", err)
+		}
+		check(OutputYAML, got)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		for format, want := range map[OutputFormat]string{
+			OutputJSON: "[]\n",
+			OutputYAML: "[]\n",
+			OutputCSV:  strings.Join(csvHeader, ",") + "\n",
+		} {
+			if got := write(format, nil); got != want {
+				t.Errorf("%v: empty output = %q, want %q", format, got, want)
+			}
+		}
+	})
+
+	t.Run("charset_names", func(t *testing.T) {
+		for _, tt := range []struct {
+			config *GeneratorConfig
+			want   string
+		}{
+			{&GeneratorConfig{Type: GeneratorCompact, Charset: NewCharacterSet(charsetPresets["base58"])}, "base58"},
+			{&GeneratorConfig{Type: GeneratorCompact, Charset: NewCharacterSet("abc")}, "custom"},
+			{&GeneratorConfig{Type: GeneratorToken, Encoding: EncodingBase64URL}, "base64url"},
+			{&GeneratorConfig{Type: GeneratorPassphrase}, "eff-large"},
+			{&GeneratorConfig{Type: GeneratorULID}, "crockford"},
+		} {
+			if got := tt.config.CharsetName(); got != tt.want {
+				t.Errorf("CharsetName(%v) = %q, want %q", tt.config.Type, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("generation_time", func(t *testing.T) {
+		gen := NewCryptoGenerator(4)
+		config := &GeneratorConfig{Type: GeneratorCompact, Length: 8, Count: 20, Charset: NewCharacterSet(alphanumericChars), Parallel: true, Workers: 4}
+
+		before := time.Now()
+		batch, err := gen.GenerateBatchTimed(context.Background(), config)
+		after := time.Now()
+		if err != nil || len(batch) != config.Count {
+			t.Fatalf("GenerateBatchTimed() = %d strings, %v", len(batch), err)
+		}
+		for _, g := range batch {
+			if g.GeneratedAt.Before(before) || g.GeneratedAt.After(after) {
+				t.Errorf("batch timestamp %v outside the run %v-%v", g.GeneratedAt, before, after)
+			}
+		}
+
+		// A slow consumer must not delay the timestamps of strings that were
+		// generated ahead of it
+		var lag time.Duration
+		for g, err := range gen.GenerateStreamTimed(context.Background(), config) {
+			if err != nil {
+				t.Fatalf("GenerateStreamTimed() error: %v", err)
+			}
+			if d := time.Since(g.GeneratedAt); d > lag {
+				lag = d
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if lag < 10*time.Millisecond {
+			t.Errorf("stream timestamps lag consumption by at most %v, want the time strings were generated", lag)
+		}
+	})
+
+	t.Run("stats", func(t *testing.T) {
+		stats := &GenerationStats{Generated: 3, Output: 3, Throughput: 1.5, EntropySource: "system"}
+		for _, format := range []OutputFormat{OutputPlain, OutputJSON, OutputNDJSON, OutputCSV, OutputYAML} {
+			var buf bytes.Buffer
+			if err := writeStats(&buf, stats, format); err != nil {
+				t.Fatalf("writeStats(%v) error: %v", format, err)
+			}
+			var got map[string]GenerationStats
+			unmarshal := json.Unmarshal
+			if format == OutputYAML {
+				unmarshal = yaml.Unmarshal
+			}
+			if err := unmarshal(buf.Bytes(), &got); err != nil || got["stats"].Throughput != 1.5 || got["stats"].Generated != 3 {
+				t.Errorf("writeStats(%v) = %q, %v", format, buf.String(), err)
+			}
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		if f, err := ParseOutputFormat("JSONL"); err != nil || f != OutputNDJSON {
+			t.Errorf("ParseOutputFormat(JSONL) = %v, %v", f, err)
+		}
+		if _, err := ParseOutputFormat("xml"); err == nil {
+			t.Error("ParseOutputFormat(xml) expected error")
+		}
+	})
+}
+
+func TestBundle(t *testing.T) {
+	const spec = `
+DB_PASSWORD:
+  type: compact
+  length: 32
+  charset: mysql-safe
+  min-digit: 2
+SESSION_KEY:
+  type: token
+  bits: 256
+  encoding: base64url
+ADMIN_PHRASE:
+  type: passphrase
+  words: 5
+DEFAULTED: {}
+`
+
+	t.Run("parse_and_generate", func(t *testing.T) {
+		secrets, err := ParseBundleSpec(strings.NewReader(spec))
+		if err != nil {
+			t.Fatalf("ParseBundleSpec() error: %v", err)
+		}
+
+		var names []string
+		for _, s := range secrets {
+			names = append(names, s.Name)
+		}
+		if want := []string{"DB_PASSWORD", "SESSION_KEY", "ADMIN_PHRASE", "DEFAULTED"}; !slices.Equal(names, want) {
+			t.Errorf("names = %v, want %v in spec order", names, want)
+		}
+		if c := secrets[3].Config; c.Type != GeneratorHyphenated || c.Charset.Len() != len(alphanumericChars) {
+			t.Errorf("entry without settings = %v/%d chars, want the command-line defaults", c.Type, c.Charset.Len())
+		}
+
+		if err := NewCryptoGenerator(4).GenerateBundle(context.Background(), secrets); err != nil {
+			t.Fatalf("GenerateBundle() error: %v", err)
+		}
+		if v := secrets[0].Value; len(v) != 32 || !secrets[0].Config.Policy.Satisfied(v) {
+			t.Errorf("DB_PASSWORD = %q, want 32 characters with 2 digits", v)
+		}
+		if v := secrets[1].Value; len(v) != 43 {
+			t.Errorf("SESSION_KEY = %q, want 43 base64url characters", v)
+		}
+		if v := secrets[2].Value; strings.Count(v, "-") != 4 {
+			t.Errorf("ADMIN_PHRASE = %q, want 5 words", v)
+		}
+	})
+
+	t.Run("invalid_entries_fail_together", func(t *testing.T) {
+		bad := "GOOD:\n  type: token\nA:\n  lenght: 3\nB:\n  type: compact\n  length: 0\n1X: {}\nGOOD:\n  type: ulid\n"
+		_, err := ParseBundleSpec(strings.NewReader(bad))
+		if err == nil {
+			t.Fatal("ParseBundleSpec() expected error")
+		}
+		for _, want := range []string{`unknown setting "lenght"`, "B: line 5: invalid length", `invalid name "1X"`, "duplicate name GOOD"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not mention %q", err, want)
+			}
+		}
+
+		for _, spec := range []string{"", "- a\n- b\n", "A: 5\n"} {
+			if _, err := ParseBundleSpec(strings.NewReader(spec)); err == nil {
+				t.Errorf("ParseBundleSpec(%q) expected error", spec)
+			}
+		}
+	})
+
+	t.Run("generation_failure_clears_values", func(t *testing.T) {
+		secrets, _ := ParseBundleSpec(strings.NewReader(spec))
+		gen := NewCryptoGeneratorWithEntropy(4, newFlakySource(3))
+		if err := gen.GenerateBundle(context.Background(), secrets); err == nil {
+			t.Fatal("GenerateBundle() expected error")
+		}
+		for _, s := range secrets {
+			if s.Value != "" {
+				t.Errorf("%s = %q after a failed bundle, want no value", s.Name, s.Value)
+			}
+		}
+	})
+
+	t.Run("formats", func(t *testing.T) {
+		secrets := []BundleSecret{
+			{Name: "PLAIN", Value: "abc-123_x"},
+			{Name: "TRICKY", Value: `a'b"c$d\e` + "`f g"},
+		}
+		for _, tt := range []struct {
+			format BundleFormat
+			want   string
+		}{
+			{BundleEnv, "PLAIN=abc-123_x\nTRICKY=\"a'b\\\"c\\$d\\\\e\\`f g\"\n"},
+			{BundleShell, "export PLAIN='abc-123_x'\nexport TRICKY='a'\\''b\"c$d\\e`f g'\n"},
+			{BundleJSON, "{\n  \"PLAIN\": \"abc-123_x\",\n  \"TRICKY\": \"a'b\\\"c$d\\\\e`f g\"\n}\n"},
+		} {
+			var buf bytes.Buffer
+			if err := WriteBundle(&buf, secrets, tt.format, BundleOptions{}); err != nil {
+				t.Fatalf("WriteBundle(%v) error: %v", tt.format, err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteBundle(%v) =\n%s\nwant\n%s", tt.format, buf.String(), tt.want)
+			}
+		}
+
+		var decoded map[string]string
+		var buf bytes.Buffer
+		WriteBundle(&buf, secrets, BundleJSON, BundleOptions{})
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded["TRICKY"] != secrets[1].Value {
+			t.Errorf("json bundle round trip = %q, %v", decoded["TRICKY"], err)
+		}
+	})
+
+	t.Run("atomic_file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "secrets.env")
+		if err := writeFileAtomic(path, []byte("A=1\n")); err != nil {
+			t.Fatalf("writeFileAtomic() error: %v", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("bundle file mode = %v, %v, want 0600", info.Mode().Perm(), err)
+		}
+		entries, _ := os.ReadDir(filepath.Dir(path))
+		if len(entries) != 1 {
+			t.Errorf("directory has %d entries, want only the bundle", len(entries))
+		}
+	})
+}
+
+func TestBundleManifests(t *testing.T) {
+	secrets := []BundleSecret{
+		{Name: "DB_PASSWORD", Value: "s3cr3t'\"$"},
+		{Name: "API_KEY", Value: "abc"},
+	}
+
+	t.Run("k8s", func(t *testing.T) {
+		opts := BundleOptions{
+			Name:      "app-secrets",
+			Namespace: "prod",
+			Labels:    map[string]string{"app.kubernetes.io/name": "app", "tier": ""},
+		}
+		var buf bytes.Buffer
+		if err := WriteBundle(&buf, secrets, BundleKubernetes, opts); err != nil {
+			t.Fatalf("WriteBundle() error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "data:\n  \"DB_PASSWORD\": ") || strings.Index(buf.String(), "DB_PASSWORD") > strings.Index(buf.String(), "API_KEY") {
+			t.Errorf("manifest data not in spec order:\n%s", buf.String())
+		}
+
+		var manifest struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+			Type       string `yaml:"type"`
+			Metadata   struct {
+				Name      string            `yaml:"name"`
+				Namespace string            `yaml:"namespace"`
+				Labels    map[string]string `yaml:"labels"`
+			} `yaml:"metadata"`
+			Data map[string]string `yaml:"data"`
+		}
+		if err := yaml.Unmarshal(buf.Bytes(), &manifest); err != nil {
+			t.Fatalf("manifest does not parse: %v", err)
+		}
+		if manifest.APIVersion != "v1" || manifest.Kind != "Secret" || manifest.Type != "Opaque" {
+			t.Errorf("manifest header = %s %s %s", manifest.APIVersion, manifest.Kind, manifest.Type)
+		}
+		if manifest.Metadata.Name != opts.Name || manifest.Metadata.Namespace != opts.Namespace || manifest.Metadata.Labels["app.kubernetes.io/name"] != "app" {
+			t.Errorf("manifest metadata = %+v", manifest.Metadata)
+		}
+		for _, s := range secrets {
+			value, err := base64.StdEncoding.DecodeString(manifest.Data[s.Name])
+			if err != nil || string(value) != s.Value {
+				t.Errorf("data[%s] = %q, %v, want %q", s.Name, value, err, s.Value)
+			}
+		}
+	})
+
+	t.Run("k8s_quotes_yaml_1_1_scalars", func(t *testing.T) {
+		// NO and null are a bool and null in YAML 1.1, as is "1234" a number
+		tricky := []BundleSecret{
+			{Name: "NO", Value: "\xd7m\xf8"}, // base64 "1234"
+			{Name: "null", Value: "x"},
+		}
+		var buf bytes.Buffer
+		if err := WriteBundle(&buf, tricky, BundleKubernetes, BundleOptions{Name: "app"}); err != nil {
+			t.Fatalf("WriteBundle() error: %v", err)
+		}
+		for _, want := range []string{`"NO": "1234"`, `"null": "eA=="`} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("manifest does not contain %s:\n%s", want, buf.String())
+			}
+		}
+	})
+
+	t.Run("invalid_k8s_options", func(t *testing.T) {
+		for _, opts := range []BundleOptions{
+			{},
+			{Name: "App_Secrets"},
+			{Name: strings.Repeat("a", 254)},
+			{Name: "app", Namespace: "prod.eu"},
+			{Name: "app", Labels: map[string]string{"-bad": "x"}},
+			{Name: "app", Labels: map[string]string{"Example.com/name": "x"}},
+			{Name: "app", Labels: map[string]string{"name": "has space"}},
+			{Name: "app", Labels: map[string]string{"name": strings.Repeat("v", 64)}},
+		} {
+			if err := WriteBundle(io.Discard, secrets, BundleKubernetes, opts); err == nil {
+				t.Errorf("WriteBundle(%+v) expected error", opts)
+			}
+		}
+	})
+
+	t.Run("kustomize", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteBundle(&buf, secrets, BundleKustomize, BundleOptions{}); err != nil {
+			t.Fatalf("WriteBundle() error: %v", err)
+		}
+		if want := "DB_PASSWORD=s3cr3t'\"$\nAPI_KEY=abc\n"; buf.String() != want {
+			t.Errorf("kustomize env = %q, want %q", buf.String(), want)
+		}
+
+		multiline := []BundleSecret{{Name: "CERT", Value: "line1\nline2"}}
+		if err := WriteBundle(&buf, multiline, BundleKustomize, BundleOptions{}); err == nil {
+			t.Error("WriteBundle() expected error for a multi-line value")
+		}
+	})
+
+	t.Run("swarm", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "secrets")
+		if err := WriteBundle(io.Discard, secrets, BundleSwarm, BundleOptions{}); err == nil {
+			t.Error("WriteBundle() expected error without a directory")
+		}
+
+		var buf bytes.Buffer
+		if err := WriteBundle(&buf, secrets, BundleSwarm, BundleOptions{Dir: dir}); err != nil {
+			t.Fatalf("WriteBundle() error: %v", err)
+		}
+		if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0o700 {
+			t.Errorf("secrets directory mode = %v, %v, want 0700", info.Mode().Perm(), err)
+		}
+		for _, s := range secrets {
+			path := filepath.Join(dir, s.Name)
+			data, err := os.ReadFile(path)
+			if err != nil || string(data) != s.Value {
+				t.Errorf("%s = %q, %v, want %q", path, data, err, s.Value)
+			}
+			if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+				t.Errorf("%s mode = %v, want 0600", path, info.Mode().Perm())
+			}
+			if !strings.Contains(buf.String(), "  "+s.Name+":\n    file: "+path+"\n") {
+				t.Errorf("compose section missing %s:\n%s", s.Name, buf.String())
+			}
+		}
+	})
+}
+
+func TestRender(t *testing.T) {
+	render := func(text string) (string, error) {
+		renderer := NewRenderer(context.Background(), NewCryptoGenerator(2))
+		var buf bytes.Buffer
+		err := renderer.Render(&buf, "test", text)
+		return buf.String(), err
+	}
+
+	t.Run("functions", func(t *testing.T) {
+		out, err := render(`{{ password 24 "hex" }}|{{ password 12 }}|{{ passphrase 4 "." }}|{{ hex 16 }}|{{ uuid }}`)
+		if err != nil {
+			t.Fatalf("Render() error: %v", err)
+		}
+		parts := strings.Split(out, "|")
+		if len(parts) != 5 {
+			t.Fatalf("Render() = %q, want 5 fields", out)
+		}
+		if len(parts[0]) != 24 || strings.Trim(parts[0], lowerHexChars) != "" {
+			t.Errorf("password 24 hex = %q", parts[0])
+		}
+		if len(parts[1]) != 12 || strings.Trim(parts[1], alphanumericChars) != "" {
+			t.Errorf("password 12 = %q", parts[1])
+		}
+		if words := strings.Split(parts[2], "."); len(words) != 4 {
+			t.Errorf("passphrase 4 = %q", parts[2])
+		}
+		if raw, err := hex.DecodeString(parts[3]); err != nil || len(raw) != 16 {
+			t.Errorf("hex 16 = %q, %v", parts[3], err)
+		}
+		if len(parts[4]) != 36 || parts[4][14] != '4' {
+			t.Errorf("uuid = %q", parts[4])
+		}
+	})
+
+	t.Run("named_secrets_repeat", func(t *testing.T) {
+		out, err := render(`{{ secret "db" (password 32) }} {{ secret "db" }} {{ secret "db" (hex 8) }} {{ secret "cache" }} {{ password 32 }}`)
+		if err != nil {
+			t.Fatalf("Render() error: %v", err)
+		}
+		fields := strings.Fields(out)
+		if len(fields[0]) != 32 || fields[1] != fields[0] || fields[2] != fields[0] {
+			t.Errorf("secret db = %q, want one value repeated", fields[:3])
+		}
+		if fields[3] == fields[0] || fields[4] == fields[0] {
+			t.Errorf("unrelated values repeat the db secret: %q", fields)
+		}
+
+		again, _ := render(`{{ secret "db" }}`)
+		if again == fields[0] {
+			t.Error("named secret repeated across renders")
+		}
+	})
+
+	t.Run("errors_write_nothing", func(t *testing.T) {
+		for _, text := range []string{
+			`{{ password 0 }}`,
+			`{{ password 8 "a-" "b" }}`,
+			`{{ hex 1000 }}`,
+			`{{ secret "" }}`,
+			`{{ unknown }}`,
+			`{{ password 8 }} {{ .Missing }}`,
+		} {
+			out, err := render(text)
+			if err == nil || out != "" {
+				t.Errorf("Render(%q) = %q, %v, want no output and an error", text, out, err)
+			}
+		}
+	})
+}
+
+func TestIndexSampler(t *testing.T) {
+	t.Run("uniform_with_rejection", func(t *testing.T) {
+		sampler := newIndexSampler(NewSeededEntropySource([]byte("sampler")), 64)
+		var counts [3]int
+		const draws = 30000
+		for range draws {
+			idx, err := sampler.index(3)
+			if err != nil {
+				t.Fatalf("index() error: %v", err)
+			}
+			counts[idx]++
+		}
+		for i, c := range counts {
+			if c < draws/3*9/10 || c > draws/3*11/10 {
+				t.Errorf("index %d drawn %d times out of %d", i, c, draws)
+			}
+		}
+	})
+
+	t.Run("consumes_only_needed_bits", func(t *testing.T) {
+		source := NewSeededEntropySource([]byte("sampler"))
+		gen := NewCryptoGeneratorWithEntropy(1, source)
+		charset := NewCharacterSet(alphanumericChars + "-_") // 64 characters, 6 bits each
+
+		s, err := gen.generateSecureString(context.Background(), 1000, charset)
+		if err != nil || len(s) != 1000 {
+			t.Fatalf("generateSecureString() = %d chars, %v", len(s), err)
+		}
+		if generated, _ := source.Stats(); generated != 750+sampleSlack {
+			t.Errorf("entropy consumed = %d bytes, want %d", generated, 750+sampleSlack)
+		}
+	})
+
+	t.Run("refills", func(t *testing.T) {
+		sampler := newIndexSampler(NewSeededEntropySource([]byte("sampler")), 1)
+		for range 100 {
+			if idx, err := sampler.index(1000); err != nil || idx >= 1000 {
+				t.Fatalf("index() = %d, %v", idx, err)
+			}
+		}
+		if v, err := sampler.bits(64); err != nil || v == 0 {
+			t.Errorf("bits(64) = %d, %v", v, err)
+		}
+	})
+
+	t.Run("retry_limit", func(t *testing.T) {
+		sampler := newIndexSampler(newScriptedSource(bytes.Repeat([]byte{0xff}, 64)), 64)
+		if _, err := sampler.index(3); err == nil {
+			t.Error("index() should give up on a source that never yields a valid value")
+		}
+	})
+
+	t.Run("edge_cases", func(t *testing.T) {
+		sampler := newIndexSampler(failingSource{}, 8)
+		if idx, err := sampler.index(1); err != nil || idx != 0 {
+			t.Errorf("index(1) = %d, %v, want 0 without reading", idx, err)
+		}
+		if _, err := sampler.index(0); err == nil {
+			t.Error("index(0) expected error")
+		}
+		if _, err := sampler.index(10); err == nil {
+			t.Error("index() should fail when the source fails")
+		}
+	})
+}
+
+func TestCryptoGenerator(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	config := &GeneratorConfig{
+		Type:         GeneratorCompact,
+		Length:       16,
+		Count:        1,
+		Charset:      NewCharacterSet(alphanumericChars),
+		Parallel:     false,
+		Workers:      1,
+		ConstantTime: true,
+	}
+
+	t.Run("single_generation", func(t *testing.T) {
+		result, err := gen.Generate(ctx, config)
+		if err != nil {
+			t.Fatalf("CryptoGenerator.Generate() error: %v", err)
+		}
+
+		if len(result) != config.Length {
+			t.Errorf("Generated string length = %d, want %d", len(result), config.Length)
+		}
+
+		// Verify all characters are from charset
+		for _, char := range result {
+			charset := config.Charset
+			valid := false
+			for i := 0; i < charset.Len(); i++ {
+				if byte(char) == charset.chars[i] {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				t.Errorf("Generated string contains invalid char: %c", char)
+			}
+		}
+	})
+
+	t.Run("batch_generation", func(t *testing.T) {
+		config.Count = 5
+		results, err := gen.GenerateBatch(ctx, config)
+		if err != nil {
+			t.Fatalf("CryptoGenerator.GenerateBatch() error: %v", err)
+		}
+
+		if len(results) != config.Count {
+			t.Errorf("Generated batch size = %d, want %d", len(results), config.Count)
+		}
+
+		// Verify uniqueness (highly probable for crypto random)
+		unique := make(map[string]bool)
+		for _, result := range results {
+			if unique[result] {
+				t.Errorf("Duplicate string in batch: %s", result)
+			}
+			unique[result] = true
+		}
+	})
+
+	t.Run("parallel_generation", func(t *testing.T) {
+		config.Count = 10
+		config.Parallel = true
+		config.Workers = 4
+
+		results, err := gen.GenerateBatch(ctx, config)
+		if err != nil {
+			t.Fatalf("Parallel generation error: %v", err)
+		}
+
+		if len(results) != config.Count {
+			t.Errorf("Parallel batch size = %d, want %d", len(results), config.Count)
+		}
+	})
+
+	t.Run("stream_generation", func(t *testing.T) {
+		config.Count = 3
+		results := make([]string, 0, config.Count)
+
+		for result, err := range gen.GenerateStream(ctx, config) {
+			if err != nil {
+				t.Fatalf("Stream generation error: %v", err)
+			}
+			results = append(results, result)
+		}
+
+		if len(results) != config.Count {
+			t.Errorf("Stream results count = %d, want %d", len(results), config.Count)
+		}
+	})
+
+	t.Run("context_cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel() // Cancel immediately
+
+		_, err := gen.Generate(ctx, config)
+		if err == nil || err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got: %v", err)
+		}
+	})
+}
+
+func TestHyphenatedGeneration(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	config := &GeneratorConfig{
+		Type:    GeneratorHyphenated,
+		Length:  18, // Set a valid length
+		Count:   1,
+		Charset: NewCharacterSet(alphanumericChars),
+		Workers: 1,
+	}
+
+	result, err := gen.Generate(ctx, config)
+	if err != nil {
+		t.Fatalf("Hyphenated generation error: %v", err)
+	}
+
+	parts := strings.Split(result, "-")
+	if len(parts) != 3 {
+		t.Errorf("Hyphenated string parts = %d, want 3", len(parts))
+	}
+
+	for i, part := range parts {
+		if len(part) != 6 {
+			t.Errorf("Part %d length = %d, want 6", i, len(part))
+		}
+	}
+}
+
+func TestPolicyGeneration(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	config := &GeneratorConfig{
+		Type:    GeneratorCompact,
+		Length:  8,
+		Count:   1,
+		Charset: NewCharacterSet(alphanumericChars + "!@#$%"),
+		Policy: PasswordPolicy{
+			MinUpper:  1,
+			MinLower:  1,
+			MinDigit:  2,
+			MinSymbol: 1,
+			Forbidden: "0O1lI",
+			MaxRepeat: 1,
+		},
+		Workers: 1,
+	}
+
+	for range 50 {
+		result, err := gen.Generate(ctx, config)
+		if err != nil {
+			t.Fatalf("Policy generation error: %v", err)
+		}
+
+		if len(result) != config.Length {
+			t.Errorf("Generated string length = %d, want %d", len(result), config.Length)
+		}
+		if !config.Policy.Satisfied(result) {
+			t.Errorf("Generated string %q does not satisfy policy", result)
+		}
+		if strings.ContainsAny(result, config.Policy.Forbidden) {
+			t.Errorf("Generated string %q contains forbidden characters", result)
+		}
+	}
+
+	// Feasible policies accepting few candidates draw enough of them
+	unlikely := &GeneratorConfig{
+		Type:    GeneratorCompact,
+		Length:  8,
+		Count:   200,
+		Charset: NewCharacterSet(alphanumericChars),
+		Policy:  PasswordPolicy{MinUpper: 4, MinDigit: 4},
+		Workers: 4,
+	}
+	if _, err := gen.GenerateBatch(ctx, unlikely); err != nil {
+		t.Errorf("Unlikely policy generation error: %v", err)
+	}
+
+	// Policies needing too many candidates are rejected up front
+	restrictive := &GeneratorConfig{
+		Type:    GeneratorCompact,
+		Length:  40,
+		Count:   1,
+		Charset: NewCharacterSet(alphanumericChars + "!@#$%^&*"),
+		Policy:  PasswordPolicy{MinUpper: 10, MinLower: 10, MinDigit: 10, MinSymbol: 10},
+		Workers: 1,
+	}
+	if err := restrictive.Validate(); err == nil || !strings.Contains(err.Error(), "too restrictive") {
+		t.Errorf("GeneratorConfig.Validate() = %v, want a too restrictive error", err)
+	}
+}
+
+func TestPasswordPolicySatisfied(t *testing.T) {
+	policy := PasswordPolicy{MinUpper: 1, MinDigit: 1, MaxRepeat: 2}
+
+	tests := map[string]bool{
+		"Abc1":  true,
+		"abc1":  false,
+		"Abcd":  false,
+		"Aaa1":  true,
+		"Aaaa1": false,
+	}
+
+	for input, want := range tests {
+		if got := policy.Satisfied(input); got != want {
+			t.Errorf("PasswordPolicy.Satisfied(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestPatternGeneration(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	pattern, err := ParsePattern(`\A\B\C-9999-xxxx-Cv!*`, NewCharacterSet("z"))
+	if err != nil {
+		t.Fatalf("ParsePattern() error: %v", err)
+	}
+
+	config := &GeneratorConfig{
+		Type:    GeneratorPattern,
+		Pattern: pattern,
+		Count:   1,
+		Workers: 1,
+	}
+
+	result, err := gen.Generate(ctx, config)
+	if err != nil {
+		t.Fatalf("Pattern generation error: %v", err)
+	}
+
+	if len(result) != pattern.Len() {
+		t.Fatalf("Pattern result length = %d, want %d", len(result), pattern.Len())
+	}
+	if !strings.HasPrefix(result, "ABC-") || result[8] != '-' || result[13] != '-' {
+		t.Errorf("Pattern result %q lost literal characters", result)
+	}
+	for _, c := range result[4:8] {
+		if !strings.ContainsRune(digits, c) {
+			t.Errorf("Pattern result %q has non-digit %q in digit group", result, c)
+		}
+	}
+	for _, c := range result[9:13] {
+		if !strings.ContainsRune(lowerHexChars, c) {
+			t.Errorf("Pattern result %q has non-hex %q in hex group", result, c)
+		}
+	}
+	if !strings.ContainsRune(upperConsonantChars, rune(result[14])) ||
+		!strings.ContainsRune(lowerVowelChars, rune(result[15])) ||
+		!strings.ContainsRune(symbolChars, rune(result[16])) ||
+		result[17] != 'z' {
+		t.Errorf("Pattern result %q does not match placeholder classes", result)
+	}
+
+	want := 4*math.Log2(10) + 4*math.Log2(16) + math.Log2(21) + math.Log2(5) + math.Log2(32)
+	if got := pattern.Entropy(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Pattern.Entropy() = %f, want %f", got, want)
+	}
+}
+
+func TestParsePatternErrors(t *testing.T) {
+	tests := map[string]string{
+		"empty":           "",
+		"trailing_escape": `99\`,
+		"star_no_charset": "**",
+		"too_long":        strings.Repeat("9", maxStringLength+1),
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParsePattern(input, nil); err == nil {
+				t.Errorf("ParsePattern(%q) expected error, got nil", input)
+			}
+		})
+	}
+}
+
+func TestConfigEntropy(t *testing.T) {
+	pattern, _ := ParsePattern("9999", nil)
+
+	tests := []struct {
+		name   string
+		config *GeneratorConfig
+		want   float64
+	}{
+		{
+			name:   "compact",
+			config: &GeneratorConfig{Type: GeneratorCompact, Length: 16, Charset: NewCharacterSet(lowerHexChars)},
+			want:   64,
+		},
+		{
+			name:   "hyphenated_default",
+			config: &GeneratorConfig{Type: GeneratorHyphenated, Charset: NewCharacterSet(alphanumericChars)},
+			want:   18 * math.Log2(62),
+		},
+		{
+			name:   "passphrase",
+			config: &GeneratorConfig{Type: GeneratorPassphrase, Words: 6, AppendDigit: true},
+			want:   6*math.Log2(7776) + math.Log2(10),
+		},
+		{
+			name:   "pattern",
+			config: &GeneratorConfig{Type: GeneratorPattern, Pattern: pattern},
+			want:   4 * math.Log2(10),
+		},
+		{
+			name: "forbidden_characters",
+			config: &GeneratorConfig{
+				Type:    GeneratorCompact,
+				Length:  10,
+				Charset: NewCharacterSet(digits),
+				Policy:  PasswordPolicy{Forbidden: "89"},
+			},
+			want: 30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.Entropy(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("GeneratorConfig.Entropy() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyEntropy(t *testing.T) {
+	charset := NewCharacterSet("aAb1!")
+	policies := []PasswordPolicy{
+		{MinUpper: 1, MinDigit: 1},
+		{MinLower: 2, MinSymbol: 1},
+		{MaxRepeat: 1},
+		{MinUpper: 1, MinLower: 1, MaxRepeat: 2},
+	}
+
+	for _, policy := range policies {
+		for length := 1; length <= 6; length++ {
+			// Count compliant strings by exhaustive enumeration
+			total := 1
+			for range length {
+				total *= charset.Len()
+			}
+			compliant := 0
+			buf := make([]byte, length)
+			for i := range total {
+				for j, k := 0, i; j < length; j, k = j+1, k/charset.Len() {
+					buf[j] = charset.chars[k%charset.Len()]
+				}
+				if policy.Satisfied(string(buf)) {
+					compliant++
+				}
+			}
+
+			want := 0.0
+			if compliant > 0 {
+				want = math.Log2(float64(compliant))
+			}
+			got := charsetEntropy(length, charset, policy)
+			if math.Abs(got-want) > 1e-9 {
+				t.Errorf("charsetEntropy(%d, %+v) = %f, want %f", length, policy, got, want)
+			}
+
+			exact, _ := policy.acceptance(length, charset)
+			if bound := policy.acceptanceBound(length, charset); bound > exact+1e-12 {
+				t.Errorf("acceptanceBound(%d, %+v) = %g exceeds %g", length, policy, bound, exact)
+			}
+		}
+	}
+
+	// Policies too large to model must not report more than their class
+	// minimums allow
+	large := NewCharacterSet(alphanumericChars + "!@#$%^&*")
+	policy := PasswordPolicy{MinUpper: 10, MinLower: 10, MinDigit: 10, MinSymbol: 10}
+	want := charsetEntropy(48, large, policy)
+	policy.MaxRepeat = 20
+	if got := charsetEntropy(48, large, policy); got > want || got < want-1e-6 {
+		t.Errorf("charsetEntropy(48, %+v) = %f, want about %f", policy, got, want)
+	}
+	policy = PasswordPolicy{MinUpper: 40, MinLower: 40, MinDigit: 40, MinSymbol: 40}
+	if got, limit := charsetEntropy(200, large, policy), charsetEntropy(200, large, PasswordPolicy{}); got <= 0 || got >= limit {
+		t.Errorf("charsetEntropy(200, %+v) = %f, want a bound in (0, %f)", policy, got, limit)
+	}
+}
+
+func TestMinEntropy(t *testing.T) {
+	config := &GeneratorConfig{
+		Type:       GeneratorCompact,
+		Length:     16,
+		Count:      1,
+		Charset:    NewCharacterSet(alphanumericChars),
+		MinEntropy: minEntropyBits,
+		Workers:    1,
+	}
+
+	if err := config.Validate(); err == nil {
+		t.Error("GeneratorConfig.Validate() expected error for weak configuration")
+	}
+
+	config.Length = 22
+	if err := config.Validate(); err != nil {
+		t.Errorf("GeneratorConfig.Validate() unexpected error: %v", err)
+	}
+}
+
+func TestEstimateStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		kind     MatchKind
+		maxBits  float64
+	}{
+		{"password", MatchDictionary, 10},
+		{"P@ssw0rd", MatchDictionary, 10},
+		{"drowssap", MatchDictionary, 10},
+		{"zaq12wsx", MatchKeyboard, 30},
+		{"aaaaaaaaaaaa", MatchRepeat, 15},
+		{"abcdefgh", MatchSequence, 10},
+		{"98765432", MatchSequence, 10},
+		{"19991231", MatchDate, 20},
+		{"12/31/1999", MatchDate, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			report := EstimateStrength(tt.password)
+			if report.Bits > tt.maxBits {
+				t.Errorf("EstimateStrength(%q).Bits = %.1f, want <= %.1f", tt.password, report.Bits, tt.maxBits)
+			}
+			if report.Bits > report.CharsetBits {
+				t.Errorf("Estimated bits %.1f exceed charset bits %.1f", report.Bits, report.CharsetBits)
+			}
+
+			found := slices.ContainsFunc(report.Matches, func(m PatternMatch) bool {
+				return m.Kind == tt.kind
+			})
+			if !found {
+				t.Errorf("EstimateStrength(%q) matches = %v, want a %s match", tt.password, report.Matches, tt.kind)
+			}
+		})
+	}
+
+	t.Run("random", func(t *testing.T) {
+		report := EstimateStrength("xK9#mQ2$vL7!pR4@")
+		if report.Bits < 90 {
+			t.Errorf("Random password estimated at %.1f bits, want >= 90", report.Bits)
+		}
+	})
+
+	t.Run("years_relative_to_now", func(t *testing.T) {
+		year := time.Now().Year()
+		if got := yearSpace(year); got != minYearSpace {
+			t.Errorf("yearSpace(%d) = %v, want %v", year, got, float64(minYearSpace))
+		}
+		if got := yearSpace(year - 50); got != 50 {
+			t.Errorf("yearSpace(%d) = %v, want 50", year-50, got)
+		}
+	})
+}
+
+func TestCheckPasswords(t *testing.T) {
+	input := "password\n\nxK9#mQ2$vL7!pR4@\nqwerty\n"
+	var out strings.Builder
+
+	weak, total, err := checkPasswords(strings.NewReader(input), &out, defaultCheckBits, nil)
+	if err != nil {
+		t.Fatalf("checkPasswords() error: %v", err)
+	}
+	if weak != 2 || total != 3 {
+		t.Errorf("checkPasswords() = %d weak of %d, want 2 of 3", weak, total)
+	}
+	if strings.Contains(out.String(), "password") || strings.Contains(out.String(), "qwerty") {
+		t.Error("checkPasswords() output must not echo passwords")
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 3 {
+		t.Errorf("checkPasswords() reported %d lines, want 3", lines)
+	}
+}
+
+// writeBreachFile writes a sorted HIBP-style corpus of the given passwords
+// plus filler entries and returns its path
+func writeBreachFile(t *testing.T, hash BreachHash, passwords map[string]int) string {
+	t.Helper()
+
+	var lines []string
+	for password, count := range passwords {
+		lines = append(lines, fmt.Sprintf("%s:%d", hash.Sum(password), count))
+	}
+	for i := range 500 {
+		lines = append(lines, fmt.Sprintf("%s:%d", hash.Sum(fmt.Sprintf("filler-%d", i)), i+1))
+	}
+	slices.Sort(lines)
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBreachDB(t *testing.T) {
+	t.Run("hash_formats", func(t *testing.T) {
+		if got := BreachSHA1.Sum("password"); got != "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8" {
+			t.Errorf("SHA-1 digest = %s", got)
+		}
+		if got := BreachNTLM.Sum("password"); got != "8846F7EAEE8FB117AD06BDD830B7586C" {
+			t.Errorf("NTLM digest = %s", got)
+		}
+	})
+
+	for _, hash := range []BreachHash{BreachSHA1, BreachNTLM} {
+		t.Run(hash.String(), func(t *testing.T) {
+			path := writeBreachFile(t, hash, map[string]int{"password": 9545824, "letmein": 42})
+			db, err := OpenBreachDB(path, hash)
+			if err != nil {
+				t.Fatalf("OpenBreachDB() error: %v", err)
+			}
+			defer db.Close()
+
+			for password, want := range map[string]uint64{"password": 9545824, "letmein": 42, "filler-0": 1, "filler-499": 500, "xK9#mQ2$": 0} {
+				got, err := db.Lookup(password)
+				if err != nil {
+					t.Fatalf("BreachDB.Lookup(%q) error: %v", password, err)
+				}
+				if got != want {
+					t.Errorf("BreachDB.Lookup(%q) = %d, want %d", password, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("regenerates_breached", func(t *testing.T) {
+		path := writeBreachFile(t, BreachSHA1, map[string]int{"a": 1})
+		db, err := OpenBreachDB(path, BreachSHA1)
+		if err != nil {
+			t.Fatalf("OpenBreachDB() error: %v", err)
+		}
+		defer db.Close()
+
+		gen := NewCryptoGenerator(4)
+		config := &GeneratorConfig{
+			Type:     GeneratorCompact,
+			Length:   1,
+			Count:    1,
+			Charset:  NewCharacterSet("ab"),
+			BreachDB: db,
+			Workers:  1,
+		}
+
+		for range 20 {
+			result, err := gen.Generate(context.Background(), config)
+			if err != nil {
+				t.Fatalf("Generate() error: %v", err)
+			}
+			if result != "b" {
+				t.Fatalf("Generate() = %q, breached candidate was not regenerated", result)
+			}
+		}
+	})
+
+	t.Run("check_reports_breaches", func(t *testing.T) {
+		path := writeBreachFile(t, BreachSHA1, map[string]int{"xK9#mQ2$vL7!pR4@": 3})
+		db, err := OpenBreachDB(path, BreachSHA1)
+		if err != nil {
+			t.Fatalf("OpenBreachDB() error: %v", err)
+		}
+		defer db.Close()
+
+		var out strings.Builder
+		weak, _, err := checkPasswords(strings.NewReader("xK9#mQ2$vL7!pR4@\n"), &out, defaultCheckBits, db)
+		if err != nil {
+			t.Fatalf("checkPasswords() error: %v", err)
+		}
+		if weak != 1 || !strings.Contains(out.String(), "breached: 3") {
+			t.Errorf("checkPasswords() = %d weak, output %q", weak, out.String())
+		}
+	})
+}
+
+func TestPronounceableGeneration(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	t.Run("generation", func(t *testing.T) {
+		config := &GeneratorConfig{
+			Type:    GeneratorPronounceable,
+			Length:  15,
+			Count:   1,
+			Workers: 1,
+		}
+
+		for range 20 {
+			result, err := gen.Generate(ctx, config)
+			if err != nil {
+				t.Fatalf("Pronounceable generation error: %v", err)
+			}
+			if len(result) != config.Length {
+				t.Errorf("Pronounceable length = %d, want %d", len(result), config.Length)
+			}
+			if strings.ContainsRune(lowerVowelChars, rune(result[0])) {
+				t.Errorf("Pronounceable string %q should start with a consonant", result)
+			}
+		}
+	})
+
+	t.Run("entropy_counts_distinct_strings", func(t *testing.T) {
+		// Enumerate every unit sequence; distinct outputs must match the
+		// count behind the reported entropy
+		var enumerate func(prefix string, n, s int, out map[string]bool)
+		enumerate = func(prefix string, n, s int, out map[string]bool) {
+			if n == 0 {
+				out[prefix] = true
+				return
+			}
+			units := [2][]string{pronounceableOnsets, pronounceableNuclei}
+			for _, u := range units[s] {
+				if len(u) <= n {
+					enumerate(prefix+u, n-len(u), 1-s, out)
+				}
+			}
+		}
+
+		for length := 1; length <= 5; length++ {
+			out := make(map[string]bool)
+			enumerate("", length, 0, out)
+
+			want := math.Log2(float64(len(out)))
+			if got := pronounceableEntropy(length); math.Abs(got-want) > 1e-9 {
+				t.Errorf("pronounceableEntropy(%d) = %f, want %f", length, got, want)
+			}
+		}
+	})
+
+	t.Run("below_charset_estimate", func(t *testing.T) {
+		config := &GeneratorConfig{Type: GeneratorPronounceable, Length: 16}
+		if bits := config.Entropy(); bits >= 16*math.Log2(26) || bits <= 0 {
+			t.Errorf("Pronounceable entropy %.2f should be positive and below the charset estimate", bits)
+		}
+	})
+}
+
+func TestTokenGeneration(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	tests := []struct {
+		encoding TokenEncoding
+		bits     int
+		length   int
+		alphabet string
+	}{
+		{EncodingHex, 256, 64, lowerHexChars},
+		{EncodingBase32, 256, 56, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567="},
+		{EncodingBase32NoPad, 256, 52, "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"},
+		{EncodingBase58, 256, 44, base58Alphabet},
+		{EncodingBase64URL, 256, 43, upperChars + lowerChars + digits + "-_"},
+		{EncodingBase58, 128, 22, base58Alphabet},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s_%d", tt.encoding, tt.bits), func(t *testing.T) {
+			config := &GeneratorConfig{
+				Type:     GeneratorToken,
+				Bits:     tt.bits,
+				Encoding: tt.encoding,
+				Count:    1,
+				Workers:  1,
+			}
+
+			for range 20 {
+				result, err := gen.Generate(ctx, config)
+				if err != nil {
+					t.Fatalf("Token generation error: %v", err)
+				}
+				if len(result) != tt.length {
+					t.Errorf("Token length = %d, want %d", len(result), tt.length)
+				}
+				if strings.Trim(result, tt.alphabet) != "" {
+					t.Errorf("Token %q contains characters outside %s alphabet", result, tt.encoding)
+				}
+			}
+
+			if bits := config.Entropy(); bits != float64(tt.bits) {
+				t.Errorf("Token entropy = %f, want %d", bits, tt.bits)
+			}
+		})
+	}
+
+	t.Run("base58_known_values", func(t *testing.T) {
+		if got := EncodingBase58.Encode([]byte{0, 0, 0, 0}); got != "111111" {
+			t.Errorf("base58(zero) = %q, want %q", got, "111111")
+		}
+		// Bitcoin encodes "hello world" as StV1DL6CwTryKyV; fixed width pads it
+		if got := EncodingBase58.Encode([]byte("hello world")); got != "1StV1DL6CwTryKyV" {
+			t.Errorf("base58(hello world) = %q, want %q", got, "1StV1DL6CwTryKyV")
+		}
+	})
+
+	t.Run("invalid_bits", func(t *testing.T) {
+		config := &GeneratorConfig{Type: GeneratorToken, Bits: 100, Count: 1, Workers: 1}
+		if err := config.Validate(); err == nil {
+			t.Error("GeneratorConfig.Validate() expected error for non-byte-aligned bits")
+		}
+	})
+}
+
+func TestAPIKeyGeneration(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	for _, algo := range []ChecksumAlgorithm{ChecksumCRC32, ChecksumCRC32C} {
+		t.Run(algo.String(), func(t *testing.T) {
+			config := &GeneratorConfig{
+				Type:     GeneratorAPIKey,
+				Length:   30,
+				Prefix:   "acme_live_",
+				Checksum: algo,
+				Count:    1,
+				Charset:  NewCharacterSet(alphanumericChars),
+				Workers:  1,
+			}
+
+			key, err := gen.Generate(ctx, config)
+			if err != nil {
+				t.Fatalf("API key generation error: %v", err)
+			}
+
+			if !strings.HasPrefix(key, "acme_live_") {
+				t.Errorf("API key %q missing prefix", key)
+			}
+			if want := len("acme_live_") + 30 + checksumLength; len(key) != want {
+				t.Errorf("API key length = %d, want %d", len(key), want)
+			}
+			body := key[len("acme_live_") : len(key)-checksumLength]
+			if sum := key[len(key)-checksumLength:]; sum != algo.Sum(body) {
+				t.Errorf("API key checksum %q does not cover the body alone", sum)
+			}
+			if err := VerifyAPIKey(key, "acme_live_", algo); err != nil {
+				t.Errorf("VerifyAPIKey(%q) unexpected error: %v", key, err)
+			}
+			if err := VerifyAPIKey(key, "", algo); err != nil {
+				t.Errorf("VerifyAPIKey(%q) without prefix unexpected error: %v", key, err)
+			}
+
+			// Any single-character typo must be caught
+			typo := []byte(key)
+			typo[15] = map[bool]byte{true: 'a', false: 'b'}[typo[15] != 'a']
+			if err := VerifyAPIKey(string(typo), "acme_live_", algo); !errors.Is(err, ErrChecksumMismatch) {
+				t.Errorf("VerifyAPIKey(typo) = %v, want ErrChecksumMismatch", err)
+			}
+			if err := VerifyAPIKey(key, "acme_test_", algo); err == nil {
+				t.Error("VerifyAPIKey() expected error for wrong prefix")
+			}
+		})
+	}
+
+	t.Run("known_checksum", func(t *testing.T) {
+		// crc32("hello") = 0x3610a686 = 907060870
+		if got := ChecksumCRC32.Sum("hello"); got != "0zNvy2" {
+			t.Errorf("ChecksumCRC32.Sum(hello) = %q, want %q", got, "0zNvy2")
+		}
+	})
+
+	t.Run("verify_stream", func(t *testing.T) {
+		key := "ghp_" + "abc" + ChecksumCRC32.Sum("abc")
+
+		var out strings.Builder
+		invalid, total, err := verifyAPIKeys(strings.NewReader(key+"\nbogus_key_000000\n"), &out, "", ChecksumCRC32)
+		if err != nil {
+			t.Fatalf("verifyAPIKeys() error: %v", err)
+		}
+		if invalid != 1 || total != 2 {
+			t.Errorf("verifyAPIKeys() = %d invalid of %d, want 1 of 2", invalid, total)
+		}
+	})
+}
+
+func TestIdentifierGeneration(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	t.Run("uuid_versions", func(t *testing.T) {
+		for _, tt := range []struct {
+			typ     GeneratorType
+			version byte
+		}{
+			{GeneratorUUIDv4, '4'},
+			{GeneratorUUIDv7, '7'},
+		} {
+			config := &GeneratorConfig{Type: tt.typ, Count: 1, Workers: 1}
+			for range 20 {
+				id, err := gen.Generate(ctx, config)
+				if err != nil {
+					t.Fatalf("%s generation error: %v", tt.typ, err)
+				}
+				if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[18] != '-' || id[23] != '-' {
+					t.Fatalf("%s %q is not in 8-4-4-4-12 form", tt.typ, id)
+				}
+				if id[14] != tt.version {
+					t.Errorf("%s %q has version %c, want %c", tt.typ, id, id[14], tt.version)
+				}
+				if !strings.ContainsRune("89ab", rune(id[19])) {
+					t.Errorf("%s %q has wrong variant", tt.typ, id)
+				}
+			}
+		}
+	})
+
+	t.Run("ulid_known_values", func(t *testing.T) {
+		var random [ulidRandomBytes]byte
+		if got := encodeULID(0, random); got != "00000000000000000000000000" {
+			t.Errorf("encodeULID(0) = %q", got)
+		}
+		for i := range random {
+			random[i] = 0xff
+		}
+		if got := encodeULID(maxULIDTime, random); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+			t.Errorf("encodeULID(max) = %q", got)
+		}
+	})
+
+	t.Run("ulid_monotonic", func(t *testing.T) {
+		config := &GeneratorConfig{Type: GeneratorULID, Count: 200, Workers: 1, Monotonic: true}
+		ids, err := gen.GenerateBatch(ctx, config)
+		if err != nil {
+			t.Fatalf("ULID batch error: %v", err)
+		}
+		sorted := slices.Clone(ids)
+		slices.Sort(sorted)
+		if !slices.Equal(ids, sorted) || len(slices.Compact(sorted)) != len(ids) {
+			t.Error("Monotonic ULIDs are not strictly increasing")
+		}
+		for _, id := range ids {
+			if len(id) != 26 || strings.Trim(id, crockfordAlphabet) != "" {
+				t.Errorf("ULID %q is not 26 Crockford base32 characters", id)
+			}
+		}
+	})
+
+	t.Run("ulid_monotonic_parallel", func(t *testing.T) {
+		config := &GeneratorConfig{Type: GeneratorULID, Count: 1000, Parallel: true, Workers: 8, Monotonic: true}
+		batch, err := gen.GenerateBatch(ctx, config)
+		if err != nil {
+			t.Fatalf("ULID batch error: %v", err)
+		}
+		var stream []string
+		for id, err := range gen.GenerateStream(ctx, config) {
+			if err != nil {
+				t.Fatalf("ULID stream error: %v", err)
+			}
+			stream = append(stream, id)
+		}
+		for name, ids := range map[string][]string{"batch": batch, "stream": stream} {
+			sorted := slices.Clone(ids)
+			slices.Sort(sorted)
+			if !slices.Equal(ids, sorted) || len(slices.Compact(sorted)) != len(ids) {
+				t.Errorf("parallel %s of monotonic ULIDs is not strictly increasing", name)
+			}
+		}
+	})
+
+	t.Run("increment_overflow", func(t *testing.T) {
+		b := []byte{0x00, 0xff}
+		if !incrementBytes(b) || b[0] != 1 || b[1] != 0 {
+			t.Errorf("incrementBytes carry = %x", b)
+		}
+		if incrementBytes([]byte{0xff, 0xff}) {
+			t.Error("incrementBytes() expected overflow")
+		}
+	})
+
+	t.Run("entropy", func(t *testing.T) {
+		for typ, want := range map[GeneratorType]float64{GeneratorUUIDv4: 122, GeneratorUUIDv7: 74, GeneratorULID: 80} {
+			config := &GeneratorConfig{Type: typ}
+			if bits := config.Entropy(); bits != want {
+				t.Errorf("%s entropy = %f, want %f", typ, bits, want)
+			}
+		}
+	})
+}
+
+func TestMnemonic(t *testing.T) {
+	// Test vectors from the BIP39 reference implementation
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+	}{
+		{
+			strings.Repeat("00", 16),
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		},
+		{
+			strings.Repeat("7f", 16),
+			"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		},
+		{
+			strings.Repeat("80", 16),
+			"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		},
+		{
+			strings.Repeat("ff", 16),
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		},
+		{
+			strings.Repeat("00", 32),
+			strings.Repeat("abandon ", 23) + "art",
+		},
+	}
+
+	for _, tt := range vectors {
+		entropy, _ := hex.DecodeString(tt.entropy)
+		got, err := MnemonicFromEntropy(entropy)
+		if err != nil {
+			t.Fatalf("MnemonicFromEntropy(%s) error: %v", tt.entropy, err)
+		}
+		if got != tt.mnemonic {
+			t.Errorf("MnemonicFromEntropy(%s) = %q, want %q", tt.entropy, got, tt.mnemonic)
+		}
+		if err := ValidateMnemonic(tt.mnemonic); err != nil {
+			t.Errorf("ValidateMnemonic(%q) error: %v", tt.mnemonic, err)
+		}
+	}
+
+	t.Run("generation", func(t *testing.T) {
+		gen := NewCryptoGenerator(4)
+		for bits := minMnemonicBits; bits <= maxMnemonicBits; bits += mnemonicBitsStep {
+			config := &GeneratorConfig{Type: GeneratorMnemonic, Bits: bits, Count: 10, Workers: 1}
+			results, err := gen.GenerateBatch(context.Background(), config)
+			if err != nil {
+				t.Fatalf("Mnemonic generation error: %v", err)
+			}
+			for _, m := range results {
+				if n := len(strings.Fields(m)); n != bits/32*3 {
+					t.Errorf("%d-bit mnemonic has %d words, want %d", bits, n, bits/32*3)
+				}
+				if err := ValidateMnemonic(m); err != nil {
+					t.Errorf("Generated mnemonic failed validation: %v", err)
+				}
+			}
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, m := range []string{
+			strings.Repeat("abandon ", 12),
+			strings.Repeat("abandon ", 11) + "bitcoin",
+			strings.Repeat("abandon ", 13),
+			"",
+		} {
+			if err := ValidateMnemonic(m); err == nil {
+				t.Errorf("ValidateMnemonic(%q) expected error", m)
+			}
+		}
+		if err := ValidateMnemonic(strings.Repeat("abandon ", 12)); !errors.Is(err, ErrMnemonicChecksum) {
+			t.Errorf("ValidateMnemonic() error = %v, want ErrMnemonicChecksum", err)
+		}
+
+		config := &GeneratorConfig{Type: GeneratorMnemonic, Bits: 192 + 8, Count: 1, Workers: 1}
+		if err := config.Validate(); err == nil {
+			t.Error("GeneratorConfig.Validate() expected error for invalid mnemonic bits")
+		}
+	})
+}
+
+func TestOTP(t *testing.T) {
+	t.Run("hotp_rfc4226", func(t *testing.T) {
+		secret := []byte("12345678901234567890")
+		want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+		for counter, code := range want {
+			if got := HOTP(secret, uint64(counter), 6, OTPSHA1); got != code {
+				t.Errorf("HOTP(counter=%d) = %s, want %s", counter, got, code)
+			}
+		}
+	})
+
+	t.Run("totp_rfc6238", func(t *testing.T) {
+		secrets := map[OTPAlgorithm][]byte{
+			OTPSHA1:   []byte("12345678901234567890"),
+			OTPSHA256: []byte("12345678901234567890123456789012"),
+			OTPSHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+		}
+		vectors := []struct {
+			unix int64
+			algo OTPAlgorithm
+			code string
+		}{
+			{59, OTPSHA1, "94287082"},
+			{59, OTPSHA256, "46119246"},
+			{59, OTPSHA512, "90693936"},
+			{1111111109, OTPSHA1, "07081804"},
+			{1234567890, OTPSHA256, "91819424"},
+			{20000000000, OTPSHA512, "47863826"},
+		}
+		for _, tt := range vectors {
+			if got := TOTP(secrets[tt.algo], time.Unix(tt.unix, 0), 30, 8, tt.algo); got != tt.code {
+				t.Errorf("TOTP(%d, %s) = %s, want %s", tt.unix, tt.algo, got, tt.code)
+			}
+		}
+	})
+
+	t.Run("uri", func(t *testing.T) {
+		key := &OTPKey{
+			Secret:    []byte("12345678901234567890"),
+			Issuer:    "Acme Corp",
+			Account:   "ci@example.com",
+			Algorithm: OTPSHA256,
+			Digits:    8,
+			Period:    60,
+		}
+		if err := key.Validate(); err != nil {
+			t.Fatalf("OTPKey.Validate() error: %v", err)
+		}
+		want := "otpauth://totp/Acme%20Corp:ci@example.com?algorithm=SHA256&digits=8&issuer=Acme+Corp&period=60&secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+		if got := key.URI(); got != want {
+			t.Errorf("OTPKey.URI() = %q, want %q", got, want)
+		}
+
+		key.HOTP, key.Counter = true, 7
+		if got := key.URI(); !strings.HasPrefix(got, "otpauth://hotp/") || !strings.Contains(got, "counter=7") {
+			t.Errorf("HOTP URI = %q", got)
+		}
+	})
+
+	t.Run("parse_secret", func(t *testing.T) {
+		secret, err := ParseOTPSecret("gezd gnbv gy3t qojq gezd gnbv gy3t qojq")
+		if err != nil {
+			t.Fatalf("ParseOTPSecret() error: %v", err)
+		}
+		if string(secret) != "12345678901234567890" {
+			t.Errorf("ParseOTPSecret() = %q", secret)
+		}
+		if _, err := ParseOTPSecret("not base32!"); err == nil {
+			t.Error("ParseOTPSecret() expected error for invalid input")
+		}
+	})
+
+	t.Run("invalid_key", func(t *testing.T) {
+		key := &OTPKey{Secret: make([]byte, 8), Digits: 4}
+		if err := key.Validate(); err == nil {
+			t.Error("OTPKey.Validate() expected error")
+		}
+	})
+
+	t.Run("imported_80_bit_secret", func(t *testing.T) {
+		secret, err := ParseOTPSecret("JBSWY3DPEHPK3PXP")
+		if err != nil || len(secret) != 10 {
+			t.Fatalf("ParseOTPSecret() = %d bytes, %v", len(secret), err)
+		}
+		key := &OTPKey{Secret: secret, Account: "alice", Digits: 6, Period: 30}
+		if err := key.Validate(); err != nil {
+			t.Errorf("OTPKey.Validate() rejected an 80-bit secret: %v", err)
+		}
+	})
+
+	t.Run("qr", func(t *testing.T) {
+		code, err := qr.Encode("otpauth://totp/x?secret=GEZDGNBV", qr.M)
+		if err != nil {
+			t.Fatalf("qr.Encode() error: %v", err)
+		}
+		var out strings.Builder
+		if err := renderQR(&out, code); err != nil {
+			t.Fatalf("renderQR() error: %v", err)
+		}
+		if lines := strings.Count(out.String(), "\n"); lines != (code.Size+2*qrQuietZone+1)/2 {
+			t.Errorf("renderQR() drew %d lines for a %d-module code", lines, code.Size)
+		}
+	})
+}
+
+func TestDeriveSitePassword(t *testing.T) {
+	// Published vectors for genpass/derive/v1. These must never change:
+	// users rely on them to reproduce existing passwords.
+	masterKey := DeriveMasterKey([]byte("correct horse battery staple"), "alice@example.com")
+	if got := hex.EncodeToString(masterKey); got != "218b6501fd56938c6197f875890f64509716c658d2f37063ea9b049db8602b58" {
+		t.Fatalf("DeriveMasterKey() = %s", got)
+	}
+
+	vectors := []struct {
+		site    string
+		counter uint32
+		length  int
+		charset string
+		policy  PasswordPolicy
+		want    string
+	}{
+		{"example.com", 1, 20, "alnum", PasswordPolicy{}, "7WH5fsogWAwysX1rd1u6"},
+		{"example.com", 2, 20, "alnum", PasswordPolicy{}, "50bzSNzndk8eHkzaPC9x"},
+		{"bank.example", 1, 6, "digits", PasswordPolicy{}, "746496"},
+		{"github.com", 1, 16, "a-zA-Z0-9!@#$%", PasswordPolicy{MinUpper: 2, MinDigit: 2, MinSymbol: 1}, "9l5hO@9HvWyb#wST"},
+	}
+
+	for _, tt := range vectors {
+		charset, err := ParseCharset(tt.charset)
+		if err != nil {
+			t.Fatalf("ParseCharset(%q) error: %v", tt.charset, err)
+		}
+		got, err := DeriveSitePassword(masterKey, tt.site, tt.counter, tt.length, charset, tt.policy)
+		if err != nil {
+			t.Fatalf("DeriveSitePassword(%s, %d) error: %v", tt.site, tt.counter, err)
+		}
+		if got != tt.want {
+			t.Errorf("DeriveSitePassword(%s, %d) = %q, want %q", tt.site, tt.counter, got, tt.want)
+		}
+		if !tt.policy.Satisfied(got) {
+			t.Errorf("DeriveSitePassword(%s, %d) = %q violates the policy", tt.site, tt.counter, got)
+		}
+	}
+
+	t.Run("login_salts_master_key", func(t *testing.T) {
+		other := DeriveMasterKey([]byte("correct horse battery staple"), "bob@example.com")
+		if slices.Equal(masterKey, other) {
+			t.Error("DeriveMasterKey() ignores the login")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		charset := NewCharacterSet(digits)
+		if _, err := DeriveSitePassword(masterKey, "", 1, 10, charset, PasswordPolicy{}); err == nil {
+			t.Error("DeriveSitePassword() expected error for empty site")
+		}
+		if _, err := DeriveSitePassword(masterKey, "x", 1, 4, charset, PasswordPolicy{MinUpper: 1}); err == nil {
+			t.Error("DeriveSitePassword() expected error for unsatisfiable policy")
+		}
+	})
+}
+
+func TestPassphraseGeneration(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	t.Run("default_wordlist", func(t *testing.T) {
+		if n := defaultWordList().Len(); n != 7776 {
+			t.Fatalf("EFF large wordlist has %d words, want 7776", n)
+		}
+
+		config := &GeneratorConfig{
+			Type:      GeneratorPassphrase,
+			Words:     6,
+			Separator: "-",
+			Count:     1,
+			Workers:   1,
+		}
+
+		result, err := gen.Generate(ctx, config)
+		if err != nil {
+			t.Fatalf("Passphrase generation error: %v", err)
+		}
+
+		words := strings.Split(result, "-")
+		if len(words) < config.Words {
+			t.Errorf("Passphrase words = %d, want at least %d", len(words), config.Words)
+		}
+	})
+
+	t.Run("capitalize_and_digit", func(t *testing.T) {
+		config := &GeneratorConfig{
+			Type:        GeneratorPassphrase,
+			Wordlist:    NewWordList([]string{"alpha", "bravo", "charlie"}),
+			Words:       4,
+			Separator:   " ",
+			Capitalize:  true,
+			AppendDigit: true,
+			Count:       1,
+			Workers:     1,
+		}
+
+		result, err := gen.Generate(ctx, config)
+		if err != nil {
+			t.Fatalf("Passphrase generation error: %v", err)
+		}
+
+		words := strings.Split(result, " ")
+		if len(words) != config.Words {
+			t.Fatalf("Passphrase words = %d, want %d", len(words), config.Words)
+		}
+
+		last := words[len(words)-1]
+		if !strings.ContainsRune(digits, rune(last[len(last)-1])) {
+			t.Errorf("Passphrase %q does not end with a digit", result)
+		}
+		words[len(words)-1] = last[:len(last)-1]
+
+		for _, word := range words {
+			if !slices.Contains([]string{"Alpha", "Bravo", "Charlie"}, word) {
+				t.Errorf("Unexpected passphrase word: %q", word)
+			}
+		}
+	})
+
+	t.Run("invalid_word_count", func(t *testing.T) {
+		config := &GeneratorConfig{
+			Type:    GeneratorPassphrase,
+			Words:   0,
+			Count:   1,
+			Workers: 1,
+		}
+
+		if err := config.Validate(); err == nil {
+			t.Error("GeneratorConfig.Validate() expected error for zero words")
+		}
+	})
+}
+
+func TestParseWordList(t *testing.T) {
+	wl, err := ParseWordList(strings.NewReader("11111\tabacus\n11112\tabdomen\n\nzoom\nzoom\n"))
+	if err != nil {
+		t.Fatalf("ParseWordList() error: %v", err)
+	}
+
+	if wl.Len() != 3 {
+		t.Errorf("WordList.Len() = %d, want 3", wl.Len())
+	}
+	if wl.At(0) != "abacus" || wl.At(2) != "zoom" {
+		t.Errorf("Unexpected words: %q, %q", wl.At(0), wl.At(2))
+	}
+
+	if _, err := ParseWordList(strings.NewReader("a b c\n")); err == nil {
+		t.Error("ParseWordList() expected error for malformed line")
+	}
+}
+
+func TestHyphenatedLayout(t *testing.T) {
+	gen := NewCryptoGenerator(4)
+	ctx := context.Background()
+
+	config := &GeneratorConfig{
+		Type:      GeneratorHyphenated,
+		Groups:    4,
+		GroupSize: 5,
+		Separator: "_",
+		Count:     1,
+		Charset:   NewCharacterSet(alphanumericChars),
+		Workers:   1,
+	}
+
+	result, err := gen.Generate(ctx, config)
+	if err != nil {
+		t.Fatalf("Hyphenated generation error: %v", err)
 	}
 
+	parts := strings.Split(result, "_")
+	if len(parts) != 4 {
+		t.Fatalf("Hyphenated string parts = %d, want 4", len(parts))
+	}
 	for i, part := range parts {
-		if len(part) != 6 {
-			t.Errorf("Part %d length = %d, want 6", i, len(part))
+		if len(part) != 5 {
+			t.Errorf("Part %d length = %d, want 5", i, len(part))
+		}
+	}
+
+	// A length must agree with the layout rather than being ignored
+	for _, tt := range []struct {
+		length  int
+		wantErr bool
+	}{
+		{0, false},
+		{20, false},
+		{15, true},
+		{23, true}, // Separators do not count
+	} {
+		config.Length = tt.length
+		if err := config.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() with length %d = %v, wantErr %t", tt.length, err, tt.wantErr)
 		}
 	}
+
+	secrets, err := ParseBundleSpec(strings.NewReader("A:\n  type: hyphenated\nB:\n  type: hyphenated\n  length: 18\n"))
+	if err != nil || secrets[0].Config.Length != 0 || secrets[1].Config.Length != 18 {
+		t.Errorf("bundle hyphenated lengths = %v, want unset and 18", err)
+	}
+	if _, err := ParseBundleSpec(strings.NewReader("A:\n  type: hyphenated\n  length: 12\n")); err == nil {
+		t.Error("ParseBundleSpec() expected error for a length that does not match the layout")
+	}
+
+	// Explicit zero layouts and empty separators are not replaced by defaults
+	for _, spec := range []string{
+		"A:\n  type: hyphenated\n  groups: 0\n",
+		"A:\n  type: hyphenated\n  group-size: 0\n",
+		"A:\n  type: hyphenated\n  separator: \"\"\n",
+		"A:\n  type: passphrase\n  separator: \"\"\n",
+	} {
+		if _, err := ParseBundleSpec(strings.NewReader(spec)); err == nil {
+			t.Errorf("ParseBundleSpec(%q) expected error", spec)
+		}
+	}
+
+	config.Length = 0
+	config.Groups = 200
+	config.GroupSize = 10
+	if err := config.Validate(); err == nil {
+		t.Error("GeneratorConfig.Validate() expected error for oversized layout")
+	}
 }
 
 // Benchmark suite using modern Go benchmarking patterns
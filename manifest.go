@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kubernetes object name limits
+const (
+	maxK8sNameLen  = 253
+	maxK8sLabelLen = 63
+)
+
+// Kubernetes naming rules (DNS-1123 subdomains and labels, label values)
+var (
+	k8sSubdomainPattern  = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+	k8sDNSLabelPattern   = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	k8sLabelValuePattern = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+)
+
+// BundleOptions configures the formats that wrap secrets in a deployment
+// resource
+type BundleOptions struct {
+	Name      string            // Kubernetes Secret name
+	Namespace string            // Kubernetes namespace (optional)
+	Labels    map[string]string // Kubernetes labels (optional)
+	Dir       string            // Directory of Docker Swarm secret files
+}
+
+// Validate validates the options required by format
+func (o BundleOptions) Validate(format BundleFormat) error {
+	var errs []error
+	switch format {
+	case BundleKubernetes:
+		if o.Name == "" {
+			errs = append(errs, errors.New("a kubernetes secret needs a name"))
+		} else if len(o.Name) > maxK8sNameLen || !k8sSubdomainPattern.MatchString(o.Name) {
+			errs = append(errs, fmt.Errorf("invalid kubernetes name: %q (lower-case letters, digits, '-' and '.')", o.Name))
+		}
+		if o.Namespace != "" && (len(o.Namespace) > maxK8sLabelLen || !k8sDNSLabelPattern.MatchString(o.Namespace)) {
+			errs = append(errs, fmt.Errorf("invalid kubernetes namespace: %q (lower-case letters, digits and '-')", o.Namespace))
+		}
+		for key, value := range o.Labels {
+			if err := validateK8sLabel(key, value); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	case BundleSwarm:
+		if o.Dir == "" {
+			errs = append(errs, errors.New("docker swarm secrets need an output directory"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateK8sLabel checks a label key, with its optional DNS subdomain
+// prefix, and its value
+func validateK8sLabel(key, value string) error {
+	name := key
+	if prefix, rest, ok := strings.Cut(key, "/"); ok {
+		if len(prefix) > maxK8sNameLen || !k8sSubdomainPattern.MatchString(prefix) {
+			return fmt.Errorf("invalid kubernetes label prefix: %q", key)
+		}
+		name = rest
+	}
+	if name == "" || len(name) > maxK8sLabelLen || !k8sLabelValuePattern.MatchString(name) {
+		return fmt.Errorf("invalid kubernetes label key: %q", key)
+	}
+	if len(value) > maxK8sLabelLen || !k8sLabelValuePattern.MatchString(value) {
+		return fmt.Errorf("invalid kubernetes label value for %s: %q", key, value)
+	}
+	return nil
+}
+
+// k8sSecret is a Kubernetes v1 Secret manifest
+type k8sSecret struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   k8sMetadata `yaml:"metadata"`
+	Type       string      `yaml:"type"`
+	Data       yaml.Node   `yaml:"data"`
+}
+
+// k8sMetadata is the object metadata of a manifest
+type k8sMetadata struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+// writeK8sSecret writes an Opaque Secret manifest with base64 data, keeping
+// the order of secrets. Data keys and values are always double-quoted
+// strings, since kubectl decodes YAML 1.1, where keys such as NO or null and
+// all-digit values would otherwise change type.
+func writeK8sSecret(buf *bytes.Buffer, secrets []BundleSecret, opts BundleOptions) error {
+	manifest := k8sSecret{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   k8sMetadata{Name: opts.Name, Namespace: opts.Namespace, Labels: opts.Labels},
+		Type:       "Opaque",
+		Data:       yaml.Node{Kind: yaml.MappingNode},
+	}
+	for _, s := range secrets {
+		manifest.Data.Content = append(manifest.Data.Content,
+			quotedScalar(s.Name),
+			quotedScalar(base64.StdEncoding.EncodeToString([]byte(s.Value))),
+		)
+	}
+
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// quotedScalar returns a double-quoted YAML string node holding value
+func quotedScalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: yaml.DoubleQuotedStyle, Value: value}
+}
+
+// writeKustomizeEnv writes an env file for a kustomize secretGenerator.
+// Kustomize takes everything after the first '=' literally, so values are
+// written unquoted and must fit on one line.
+func writeKustomizeEnv(buf *bytes.Buffer, secrets []BundleSecret) error {
+	for _, s := range secrets {
+		if strings.ContainsAny(s.Value, "\r\n") {
+			return fmt.Errorf("%s: kustomize env files cannot hold multi-line values", s.Name)
+		}
+		fmt.Fprintf(buf, "%s=%s\n", s.Name, s.Value)
+	}
+	return nil
+}
+
+// writeSwarmSecrets writes each secret to its own file in dir, as expected
+// by `docker secret create NAME FILE` and the file option of compose
+// secrets, and writes the matching compose secrets section to w. Files hold
+// the exact value, without a trailing newline, and are readable only by the
+// owner.
+func writeSwarmSecrets(w io.Writer, dir string, secrets []BundleSecret) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating secrets directory: %w", err)
+	}
+
+	var compose bytes.Buffer
+	compose.WriteString("secrets:\n")
+	for _, s := range secrets {
+		path := filepath.Join(dir, s.Name)
+		if err := writeFileAtomic(path, []byte(s.Value)); err != nil {
+			return fmt.Errorf("writing %s: %w", s.Name, err)
+		}
+		fmt.Fprintf(&compose, "  %s:\n    file: %s\n", s.Name, composePath(path))
+	}
+
+	_, err := w.Write(compose.Bytes())
+	return err
+}
+
+// composePath returns path in the relative form compose files use, such as
+// ./secrets/NAME, leaving absolute paths alone
+func composePath(path string) string {
+	if filepath.IsAbs(path) || strings.HasPrefix(path, ".") {
+		return filepath.ToSlash(path)
+	}
+	return "./" + filepath.ToSlash(path)
+}
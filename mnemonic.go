@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// BIP39 constants
+const (
+	minMnemonicBits   = 128
+	maxMnemonicBits   = 256
+	mnemonicBitsStep  = 32 // One checksum bit per 32 bits of entropy
+	mnemonicWordBits  = 11
+	mnemonicWordCount = 1 << mnemonicWordBits
+)
+
+// bip39English is the BIP39 English wordlist as published at
+// https://github.com/bitcoin/bips/blob/master/bip-0039/english.txt
+//
+//go:embed wordlists/bip39_english.txt
+var bip39English string
+
+// ErrMnemonicChecksum is returned when a mnemonic's checksum bits do not
+// match its entropy
+var ErrMnemonicChecksum = errors.New("mnemonic checksum mismatch")
+
+// bip39Words lazily parses the embedded BIP39 wordlist and its reverse index
+var bip39Words = sync.OnceValues(func() ([]string, map[string]int) {
+	words := strings.Fields(bip39English)
+	if len(words) != mnemonicWordCount {
+		panic(fmt.Sprintf("embedded BIP39 wordlist has %d words, want %d", len(words), mnemonicWordCount))
+	}
+
+	index := make(map[string]int, len(words))
+	for i, w := range words {
+		index[w] = i
+	}
+	return words, index
+})
+
+// validMnemonicBits reports whether bits is a BIP39 entropy size
+func validMnemonicBits(bits int) bool {
+	return bits >= minMnemonicBits && bits <= maxMnemonicBits && bits%mnemonicBitsStep == 0
+}
+
+// MnemonicFromEntropy encodes entropy as a BIP39 mnemonic: the entropy
+// followed by the leading bits of its SHA-256 hash, split into 11-bit
+// indexes into the English wordlist
+func MnemonicFromEntropy(entropy []byte) (string, error) {
+	bits := 8 * len(entropy)
+	if !validMnemonicBits(bits) {
+		return "", fmt.Errorf("invalid mnemonic entropy: %d bits (must be a multiple of %d in %d-%d)", bits, mnemonicBitsStep, minMnemonicBits, maxMnemonicBits)
+	}
+
+	// At most 8 checksum bits, so the first hash byte always suffices
+	hash := sha256.Sum256(entropy)
+	data := append(entropy[:len(entropy):len(entropy)], hash[0])
+	defer clear(data)
+
+	words, _ := bip39Words()
+	n := (bits + bits/mnemonicBitsStep) / mnemonicWordBits
+	out := make([]string, n)
+	for i := range n {
+		idx := 0
+		for b := i * mnemonicWordBits; b < (i+1)*mnemonicWordBits; b++ {
+			idx = idx<<1 | int(data[b/8]>>(7-b%8)&1)
+		}
+		out[i] = words[idx]
+	}
+
+	return strings.Join(out, " "), nil
+}
+
+// ValidateMnemonic checks that every word of a BIP39 mnemonic is in the
+// English wordlist and that its checksum bits match the encoded entropy
+func ValidateMnemonic(mnemonic string) error {
+	fields := strings.Fields(strings.ToLower(mnemonic))
+	total := len(fields) * mnemonicWordBits
+	checksumBits := total / (mnemonicBitsStep + 1)
+	if len(fields)%3 != 0 || !validMnemonicBits(total-checksumBits) {
+		return fmt.Errorf("invalid mnemonic length: %d words (must be 12, 15, 18, 21 or 24)", len(fields))
+	}
+
+	_, index := bip39Words()
+	data := make([]byte, (total+7)/8)
+	defer clear(data)
+	for i, w := range fields {
+		idx, ok := index[w]
+		if !ok {
+			return fmt.Errorf("word %d is not in the BIP39 wordlist", i+1)
+		}
+		for b := range mnemonicWordBits {
+			if idx>>(mnemonicWordBits-1-b)&1 != 0 {
+				pos := i*mnemonicWordBits + b
+				data[pos/8] |= 1 << (7 - pos%8)
+			}
+		}
+	}
+
+	entropy := data[:(total-checksumBits)/8]
+	hash := sha256.Sum256(entropy)
+	if data[len(entropy)]>>(8-checksumBits) != hash[0]>>(8-checksumBits) {
+		return ErrMnemonicChecksum
+	}
+	return nil
+}
+
+// generateMnemonic generates a BIP39 mnemonic from the configured number of
+// entropy bits
+func (cg *CryptoGenerator) generateMnemonic(ctx context.Context, config *GeneratorConfig) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+	}
+
+	raw, err := cg.entropy.GenerateBytes(config.Bits / 8)
+	if err != nil {
+		return "", fmt.Errorf("generating mnemonic entropy: %w", err)
+	}
+	defer clear(raw)
+
+	return MnemonicFromEntropy(raw)
+}
+
+// newVerifyMnemonicCommand creates the verify-mnemonic subcommand, which
+// validates BIP39 mnemonic checksums read from stdin
+func (app *Application) newVerifyMnemonicCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-mnemonic",
+		Short: "Verify BIP39 mnemonic checksums offline",
+		Long: `Verify BIP39 mnemonics read from stdin, one per line, against the English
+wordlist and their checksum bits.
+
+Mnemonics are never accepted as arguments. The command exits non-zero if any
+mnemonic fails verification.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         app.runVerifyMnemonic,
+	}
+}
+
+// runVerifyMnemonic executes the verify-mnemonic subcommand
+func (app *Application) runVerifyMnemonic(cmd *cobra.Command, args []string) error {
+	invalid, total, err := verifyMnemonics(cmd.InOrStdin(), cmd.OutOrStdout())
+	if err != nil {
+		return err
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d mnemonics failed verification", invalid, total)
+	}
+	return nil
+}
+
+// verifyMnemonics reports the verification result of each line in r,
+// returning the number of invalid mnemonics and the number checked
+func verifyMnemonics(r io.Reader, w io.Writer) (invalid, total int, err error) {
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		mnemonic := strings.TrimSpace(scanner.Text())
+		if mnemonic == "" {
+			continue
+		}
+		total++
+
+		if err := ValidateMnemonic(mnemonic); err != nil {
+			invalid++
+			fmt.Fprintf(w, "line %d: invalid (%v)\n", line, err)
+			continue
+		}
+		fmt.Fprintf(w, "line %d: ok\n", line)
+	}
+	if err := scanner.Err(); err != nil {
+		return invalid, total, fmt.Errorf("reading mnemonics: %w", err)
+	}
+
+	return invalid, total, nil
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"rsc.io/qr"
+)
+
+// OTP constants
+const (
+	minOTPSecretBytes         = 16 // New secrets: RFC 4226 requires at least 128 bits
+	minImportedOTPSecretBytes = 10 // Existing secrets: the 80 bits many providers issue
+	maxOTPSecretBytes         = 64
+	defaultOTPSecretBytes     = 20 // RFC 4226 recommends 160 bits
+	minOTPDigits              = 6
+	maxOTPDigits              = 8
+	defaultOTPDigits          = 6
+	defaultOTPPeriod          = 30
+	qrQuietZone               = 4 // Modules of blank border required around a QR code
+)
+
+// otpBase32 is the unpadded base32 encoding used for OTP secrets
+var otpBase32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// OTPAlgorithm identifies the HMAC hash used to compute one-time passwords
+type OTPAlgorithm uint8
+
+// Supported OTP algorithms
+const (
+	OTPSHA1 OTPAlgorithm = iota
+	OTPSHA256
+	OTPSHA512
+)
+
+// String implements fmt.Stringer for OTPAlgorithm
+func (a OTPAlgorithm) String() string {
+	switch a {
+	case OTPSHA1:
+		return "sha1"
+	case OTPSHA256:
+		return "sha256"
+	case OTPSHA512:
+		return "sha512"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseOTPAlgorithm parses a string into OTPAlgorithm
+func ParseOTPAlgorithm(s string) (OTPAlgorithm, error) {
+	switch strings.ToLower(s) {
+	case "sha1", "sha-1":
+		return OTPSHA1, nil
+	case "sha256", "sha-256":
+		return OTPSHA256, nil
+	case "sha512", "sha-512":
+		return OTPSHA512, nil
+	default:
+		return 0, fmt.Errorf("invalid otp algorithm: %q", s)
+	}
+}
+
+// hash returns the constructor of the algorithm's hash function
+func (a OTPAlgorithm) hash() func() hash.Hash {
+	switch a {
+	case OTPSHA256:
+		return sha256.New
+	case OTPSHA512:
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
+
+// HOTP computes an RFC 4226 one-time password for the given counter
+func HOTP(secret []byte, counter uint64, digits int, algo OTPAlgorithm) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+
+	mac := hmac.New(algo.hash(), secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// TOTP computes an RFC 6238 one-time password for time t
+func TOTP(secret []byte, t time.Time, period, digits int, algo OTPAlgorithm) string {
+	return HOTP(secret, uint64(t.Unix())/uint64(period), digits, algo)
+}
+
+// OTPKey describes a shared OTP secret and the parameters needed to
+// provision it in an authenticator app
+type OTPKey struct {
+	Secret    []byte
+	Issuer    string
+	Account   string
+	Algorithm OTPAlgorithm
+	Digits    int
+	Period    int    // Seconds per TOTP step
+	Counter   uint64 // Initial HOTP counter
+	HOTP      bool   // Counter-based (HOTP) rather than time-based (TOTP)
+}
+
+// Validate validates the key parameters. Secrets are accepted from 80 bits
+// so that already provisioned keys keep working; generated secrets are held
+// to the 128-bit minimum when their size is chosen.
+func (k *OTPKey) Validate() error {
+	var errs []error
+
+	if len(k.Secret) < minImportedOTPSecretBytes || len(k.Secret) > maxOTPSecretBytes {
+		errs = append(errs, fmt.Errorf("invalid secret size: %d bytes (must be %d-%d)", len(k.Secret), minImportedOTPSecretBytes, maxOTPSecretBytes))
+	}
+	if k.Account == "" {
+		errs = append(errs, errors.New("account name is required"))
+	}
+	if strings.Contains(k.Issuer, ":") || strings.Contains(k.Account, ":") {
+		errs = append(errs, errors.New("issuer and account cannot contain ':'"))
+	}
+	if k.Digits < minOTPDigits || k.Digits > maxOTPDigits {
+		errs = append(errs, fmt.Errorf("invalid digits: %d (must be %d-%d)", k.Digits, minOTPDigits, maxOTPDigits))
+	}
+	if !k.HOTP && k.Period <= 0 {
+		errs = append(errs, fmt.Errorf("invalid period: %d", k.Period))
+	}
+
+	return errors.Join(errs...)
+}
+
+// URI returns the otpauth:// provisioning URI of the key in the Key URI
+// format understood by authenticator apps
+func (k *OTPKey) URI() string {
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+
+	q := url.Values{}
+	q.Set("secret", otpBase32.EncodeToString(k.Secret))
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	q.Set("algorithm", strings.ToUpper(k.Algorithm.String()))
+	q.Set("digits", strconv.Itoa(k.Digits))
+
+	host := "totp"
+	if k.HOTP {
+		host = "hotp"
+		q.Set("counter", strconv.FormatUint(k.Counter, 10))
+	} else {
+		q.Set("period", strconv.Itoa(k.Period))
+	}
+
+	u := url.URL{Scheme: "otpauth", Host: host, Path: "/" + label, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// Code returns the one-time password valid at time t, or for the initial
+// counter of an HOTP key
+func (k *OTPKey) Code(t time.Time) string {
+	if k.HOTP {
+		return HOTP(k.Secret, k.Counter, k.Digits, k.Algorithm)
+	}
+	return TOTP(k.Secret, t, k.Period, k.Digits, k.Algorithm)
+}
+
+// ParseOTPSecret decodes a base32 OTP secret, tolerating lower case, spaces
+// and padding as commonly shown by providers
+func ParseOTPSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.Join(strings.Fields(s), ""))
+	secret, err := otpBase32.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base32 secret: %w", err)
+	}
+	return secret, nil
+}
+
+// renderQR draws a QR code with ANSI half blocks, two modules per character
+// cell, black on a white background so it scans on dark terminals too
+func renderQR(w io.Writer, code *qr.Code) error {
+	bw := bufio.NewWriter(w)
+	lo, hi := -qrQuietZone, code.Size+qrQuietZone
+	for y := lo; y < hi; y += 2 {
+		bw.WriteString("\x1b[30;47m")
+		for x := lo; x < hi; x++ {
+			switch top, bottom := code.Black(x, y), code.Black(x, y+1); {
+			case top && bottom:
+				bw.WriteString("█")
+			case top:
+				bw.WriteString("▀")
+			case bottom:
+				bw.WriteString("▄")
+			default:
+				bw.WriteByte(' ')
+			}
+		}
+		bw.WriteString("\x1b[0m\n")
+	}
+	return bw.Flush()
+}
+
+// newOTPCommand creates the otp subcommand, which provisions TOTP/HOTP
+// shared secrets
+func (app *Application) newOTPCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "otp",
+		Short: "Generate TOTP/HOTP secrets and provisioning URIs",
+		Long: `Generate an RFC 6238 (TOTP) or RFC 4226 (HOTP) shared secret and print it
+in base32 together with its otpauth:// provisioning URI.
+
+The URI can also be rendered as a QR code in the terminal (--qr) or written
+to a PNG file (--png). With --stdin, an existing base32 secret is read from
+stdin instead of generating one, which together with --code verifies what an
+authenticator app should currently display.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         app.runOTP,
+	}
+
+	cmd.Flags().IntP("bytes", "", defaultOTPSecretBytes, "Secret size in bytes")
+	cmd.Flags().StringP("issuer", "", "", "Issuer shown by authenticator apps")
+	cmd.Flags().StringP("account", "", "", "Account name (required)")
+	cmd.Flags().StringP("algorithm", "", "sha1", "HMAC algorithm (sha1|sha256|sha512)")
+	cmd.Flags().IntP("digits", "", defaultOTPDigits, "Code length")
+	cmd.Flags().IntP("period", "", defaultOTPPeriod, "TOTP step in seconds")
+	cmd.Flags().BoolP("hotp", "", false, "Counter-based HOTP instead of TOTP")
+	cmd.Flags().Uint64P("counter", "", 0, "Initial HOTP counter")
+	cmd.Flags().BoolP("qr", "", false, "Render the URI as a QR code in the terminal")
+	cmd.Flags().StringP("png", "", "", "Write the URI as a QR code PNG to this file")
+	cmd.Flags().BoolP("code", "", false, "Print the current code")
+	cmd.Flags().BoolP("stdin", "", false, "Read an existing base32 secret from stdin")
+
+	return cmd
+}
+
+// runOTP executes the otp subcommand
+func (app *Application) runOTP(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+	algoName, _ := flags.GetString("algorithm")
+	algo, err := ParseOTPAlgorithm(algoName)
+	if err != nil {
+		return err
+	}
+
+	key := &OTPKey{Algorithm: algo}
+	key.Issuer, _ = flags.GetString("issuer")
+	key.Account, _ = flags.GetString("account")
+	key.Digits, _ = flags.GetInt("digits")
+	key.Period, _ = flags.GetInt("period")
+	key.HOTP, _ = flags.GetBool("hotp")
+	key.Counter, _ = flags.GetUint64("counter")
+
+	if fromStdin, _ := flags.GetBool("stdin"); fromStdin {
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("reading secret: %w", err)
+		}
+		key.Secret, err = ParseOTPSecret(line)
+		if err != nil {
+			return err
+		}
+	} else {
+		size, _ := flags.GetInt("bytes")
+		if size < minOTPSecretBytes || size > maxOTPSecretBytes {
+			return fmt.Errorf("invalid secret size: %d bytes (must be %d-%d)", size, minOTPSecretBytes, maxOTPSecretBytes)
+		}
+		key.Secret, err = app.generator.entropy.GenerateBytes(size)
+		if err != nil {
+			return fmt.Errorf("generating otp secret: %w", err)
+		}
+	}
+	defer clear(key.Secret)
+
+	if err := key.Validate(); err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	uri := key.URI()
+	fmt.Fprintf(out, "secret: %s\n", otpBase32.EncodeToString(key.Secret))
+	fmt.Fprintf(out, "uri: %s\n", uri)
+
+	showQR, _ := flags.GetBool("qr")
+	pngPath, _ := flags.GetString("png")
+	if showQR || pngPath != "" {
+		code, err := qr.Encode(uri, qr.M)
+		if err != nil {
+			return fmt.Errorf("encoding qr code: %w", err)
+		}
+		if showQR {
+			if err := renderQR(out, code); err != nil {
+				return err
+			}
+		}
+		if pngPath != "" {
+			// The image embeds the secret, so keep it private to the user
+			if err := os.WriteFile(pngPath, code.PNG(), 0o600); err != nil {
+				return fmt.Errorf("writing qr code: %w", err)
+			}
+		}
+	}
+
+	if showCode, _ := flags.GetBool("code"); showCode {
+		fmt.Fprintf(out, "code: %s\n", key.Code(time.Now()))
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormat identifies how generated strings are written
+type OutputFormat uint8
+
+// Supported output formats
+const (
+	OutputPlain OutputFormat = iota
+	OutputJSON
+	OutputNDJSON
+	OutputCSV
+	OutputYAML
+)
+
+// String implements fmt.Stringer for OutputFormat
+func (f OutputFormat) String() string {
+	switch f {
+	case OutputPlain:
+		return "plain"
+	case OutputJSON:
+		return "json"
+	case OutputNDJSON:
+		return "ndjson"
+	case OutputCSV:
+		return "csv"
+	case OutputYAML:
+		return "yaml"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseOutputFormat parses a string into OutputFormat
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch strings.ToLower(s) {
+	case "plain", "text":
+		return OutputPlain, nil
+	case "json":
+		return OutputJSON, nil
+	case "ndjson", "jsonl":
+		return OutputNDJSON, nil
+	case "csv":
+		return OutputCSV, nil
+	case "yaml", "yml":
+		return OutputYAML, nil
+	default:
+		return 0, fmt.Errorf("invalid output format: %q", s)
+	}
+}
+
+// OutputItem is a generated string with its metadata
+type OutputItem struct {
+	Index       int       `json:"index" yaml:"index"` // Position in generation order
+	Value       string    `json:"value" yaml:"value"`
+	Type        string    `json:"type" yaml:"type"`
+	Length      int       `json:"length" yaml:"length"`
+	Charset     string    `json:"charset,omitempty" yaml:"charset,omitempty"`
+	EntropyBits float64   `json:"entropy_bits" yaml:"entropy_bits"`
+	GeneratedAt time.Time `json:"generated_at" yaml:"generated_at"`
+}
+
+// csvHeader names the columns written by the CSV format
+var csvHeader = []string{"index", "value", "type", "length", "charset", "entropy_bits", "generated_at"}
+
+// CharsetName returns the name of the alphabet output is drawn from: the
+// charset preset, the token encoding or a fixed alphabet, or "custom"
+func (gc *GeneratorConfig) CharsetName() string {
+	switch gc.Type {
+	case GeneratorPassphrase:
+		if gc.Wordlist == nil {
+			return "eff-large"
+		}
+		return "custom-wordlist"
+	case GeneratorMnemonic:
+		return "bip39-english"
+	case GeneratorToken:
+		return gc.Encoding.String()
+	case GeneratorUUIDv4, GeneratorUUIDv7:
+		return "hex"
+	case GeneratorULID:
+		return "crockford"
+	case GeneratorPattern, GeneratorPronounceable:
+		return gc.Type.String()
+	default:
+		return CharsetName(gc.Charset)
+	}
+}
+
+// ItemWriter writes generated strings in an output format. Close writes any
+// trailer and flushes, and must be called once all items are written.
+type ItemWriter interface {
+	WriteItem(item OutputItem) error
+	Close() error
+}
+
+// NewItemWriter creates an ItemWriter for format writing to w through a
+// buffer. Plain output is the bare value per line.
+func NewItemWriter(w io.Writer, format OutputFormat) ItemWriter {
+	bw := bufio.NewWriterSize(w, streamBufferSize)
+	switch format {
+	case OutputJSON:
+		return &jsonWriter{w: bw}
+	case OutputNDJSON:
+		return &ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}
+	case OutputCSV:
+		return &csvWriter{w: csv.NewWriter(bw)}
+	case OutputYAML:
+		return &yamlWriter{w: bw}
+	default:
+		return &plainWriter{w: bw}
+	}
+}
+
+// plainWriter writes one value per line
+type plainWriter struct {
+	w *bufio.Writer
+}
+
+func (pw *plainWriter) WriteItem(item OutputItem) error {
+	pw.w.WriteString(item.Value)
+	return pw.w.WriteByte('\n')
+}
+
+func (pw *plainWriter) Close() error {
+	return pw.w.Flush()
+}
+
+// jsonWriter writes a JSON array, one item per line so it can be streamed
+type jsonWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (jw *jsonWriter) WriteItem(item OutputItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if jw.count == 0 {
+		jw.w.WriteString("[\n  ")
+	} else {
+		jw.w.WriteString(",\n  ")
+	}
+	jw.count++
+	_, err = jw.w.Write(data)
+	return err
+}
+
+func (jw *jsonWriter) Close() error {
+	if jw.count == 0 {
+		jw.w.WriteString("[]\n")
+	} else {
+		jw.w.WriteString("\n]\n")
+	}
+	return jw.w.Flush()
+}
+
+// ndjsonWriter writes one JSON object per line
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (nw *ndjsonWriter) WriteItem(item OutputItem) error {
+	return nw.enc.Encode(item)
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nw.w.Flush()
+}
+
+// csvWriter writes a header row followed by one row per item
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func (cw *csvWriter) writeHeader() {
+	if !cw.header {
+		cw.w.Write(csvHeader)
+		cw.header = true
+	}
+}
+
+func (cw *csvWriter) WriteItem(item OutputItem) error {
+	cw.writeHeader()
+	return cw.w.Write([]string{
+		strconv.Itoa(item.Index),
+		item.Value,
+		item.Type,
+		strconv.Itoa(item.Length),
+		item.Charset,
+		strconv.FormatFloat(item.EntropyBits, 'f', 2, 64),
+		item.GeneratedAt.Format(time.RFC3339Nano),
+	})
+}
+
+func (cw *csvWriter) Close() error {
+	cw.writeHeader()
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// yamlWriter writes a YAML sequence, encoding each item as one element
+type yamlWriter struct {
+	w     *bufio.Writer
+	count int
+}
+
+func (yw *yamlWriter) WriteItem(item OutputItem) error {
+	data, err := yaml.Marshal([]OutputItem{item})
+	if err != nil {
+		return err
+	}
+	yw.count++
+	_, err = yw.w.Write(data)
+	return err
+}
+
+func (yw *yamlWriter) Close() error {
+	if yw.count == 0 {
+		yw.w.WriteString("[]\n")
+	}
+	return yw.w.Flush()
+}
+
+// itemTemplate returns the metadata shared by every string of a run, which
+// is computed once rather than per item
+func (gc *GeneratorConfig) itemTemplate() OutputItem {
+	return OutputItem{
+		Type:        gc.Type.String(),
+		Charset:     gc.CharsetName(),
+		EntropyBits: gc.Entropy(),
+	}
+}
+
+// newItem fills a copy of template with a generated string
+func newItem(template OutputItem, g GeneratedString) OutputItem {
+	template.Index = g.Index
+	template.Value = g.Value
+	template.Length = utf8.RuneCountInString(g.Value)
+	template.GeneratedAt = g.GeneratedAt
+	return template
+}
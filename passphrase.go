@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// effLargeWordlist is the EFF large wordlist (7776 words, one per five dice
+// rolls) as published at https://www.eff.org/dice
+//
+//go:embed wordlists/eff_large_wordlist.txt
+var effLargeWordlist string
+
+// defaultWordList lazily parses the embedded EFF large wordlist once
+var defaultWordList = sync.OnceValue(func() *WordList {
+	wl, err := ParseWordList(strings.NewReader(effLargeWordlist))
+	if err != nil {
+		panic(fmt.Sprintf("embedded wordlist is invalid: %v", err))
+	}
+	return wl
+})
+
+// WordList represents a deduplicated list of words for passphrase generation
+type WordList struct {
+	words []string
+}
+
+// NewWordList creates a new word list, removing empty entries and duplicates
+// while preserving order
+func NewWordList(words []string) *WordList {
+	unique := make([]string, 0, len(words))
+	seen := make(map[string]bool, len(words))
+	for _, w := range words {
+		if w == "" || seen[w] {
+			continue
+		}
+		seen[w] = true
+		unique = append(unique, w)
+	}
+
+	return &WordList{words: unique}
+}
+
+// ParseWordList reads a word list with one word per line. Lines in the
+// diceware format ("11111<tab>abacus") have their dice prefix stripped.
+func ParseWordList(r io.Reader) (*WordList, error) {
+	var words []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			words = append(words, fields[0])
+		case 2:
+			words = append(words, fields[1])
+		default:
+			return nil, fmt.Errorf("invalid wordlist line: %q", scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading wordlist: %w", err)
+	}
+
+	wl := NewWordList(words)
+	if wl.Len() == 0 {
+		return nil, errors.New("wordlist cannot be empty")
+	}
+	return wl, nil
+}
+
+// LoadWordList reads a word list from the named file
+func LoadWordList(path string) (*WordList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseWordList(f)
+}
+
+// At returns the word at index i
+func (wl *WordList) At(i uint64) string {
+	return wl.words[i%uint64(len(wl.words))]
+}
+
+// Len returns the number of words in the list
+func (wl *WordList) Len() int {
+	return len(wl.words)
+}
+
+// generatePassphrase generates a passphrase of whole words drawn uniformly
+// from the configured word list
+func (cg *CryptoGenerator) generatePassphrase(ctx context.Context, config *GeneratorConfig) (string, error) {
+	wordlist := config.Wordlist
+	if wordlist == nil {
+		wordlist = defaultWordList()
+	}
+
+	n := uint64(wordlist.Len())
+	size := sampleBytes(n, config.Words)
+	if config.AppendDigit {
+		size += sampleBytes(uint64(len(digits)), 1)
+	}
+	sampler := newIndexSampler(cg.entropy, size)
+	defer sampler.wipe()
+
+	words := make([]string, config.Words)
+	for i := range words {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		idx, err := sampler.index(n)
+		if err != nil {
+			return "", fmt.Errorf("selecting word %d: %w", i, err)
+		}
+
+		word := wordlist.At(idx)
+		if config.Capitalize {
+			word = capitalize(word)
+		}
+		words[i] = word
+	}
+
+	if config.AppendDigit {
+		idx, err := sampler.index(uint64(len(digits)))
+		if err != nil {
+			return "", fmt.Errorf("selecting digit: %w", err)
+		}
+		words[len(words)-1] += string(digits[idx])
+	}
+
+	return strings.Join(words, config.Separator), nil
+}
+
+// capitalize upper-cases the first letter of a word
+func capitalize(word string) string {
+	r, size := utf8.DecodeRuneInString(word)
+	if r == utf8.RuneError {
+		return word
+	}
+	return string(unicode.ToUpper(r)) + word[size:]
+}
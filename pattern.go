@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Character sets used by pattern placeholders
+const (
+	symbolChars         = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+	lowerHexChars       = "0123456789abcdef"
+	upperHexChars       = "0123456789ABCDEF"
+	lowerVowelChars     = "aeiou"
+	upperVowelChars     = "AEIOU"
+	lowerConsonantChars = "bcdfghjklmnpqrstvwxyz"
+	upperConsonantChars = "BCDFGHJKLMNPQRSTVWXYZ"
+)
+
+// patternClasses maps placeholder characters to the character sets they
+// draw from. '*' is resolved against the configured charset.
+var patternClasses = map[byte]*CharacterSet{
+	'A': NewCharacterSet(upperChars),
+	'a': NewCharacterSet(lowerChars),
+	'9': NewCharacterSet(digits),
+	'X': NewCharacterSet(upperHexChars),
+	'x': NewCharacterSet(lowerHexChars),
+	'!': NewCharacterSet(symbolChars),
+	'C': NewCharacterSet(upperConsonantChars),
+	'c': NewCharacterSet(lowerConsonantChars),
+	'V': NewCharacterSet(upperVowelChars),
+	'v': NewCharacterSet(lowerVowelChars),
+}
+
+// patternToken is a single position in a pattern: either a literal byte or
+// a character set to sample from
+type patternToken struct {
+	charset *CharacterSet
+	literal byte
+}
+
+// Pattern is a parsed template describing the shape of a generated string.
+//
+// Placeholders:
+//
+//	A  uppercase letter     a  lowercase letter
+//	C  uppercase consonant  c  lowercase consonant
+//	V  uppercase vowel      v  lowercase vowel
+//	X  uppercase hex digit  x  lowercase hex digit
+//	9  digit                !  symbol
+//	*  any character from the configured charset
+//	\  escapes the next character as a literal
+//
+// Every other character is copied literally.
+type Pattern struct {
+	source string
+	tokens []patternToken
+}
+
+// ParsePattern parses a pattern template. charset is used for the '*'
+// placeholder and may be nil if the template does not use it.
+func ParsePattern(s string, charset *CharacterSet) (*Pattern, error) {
+	if s == "" {
+		return nil, errors.New("pattern cannot be empty")
+	}
+
+	tokens := make([]patternToken, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(s) {
+				return nil, errors.New("pattern ends with an unfinished escape")
+			}
+			i++
+			tokens = append(tokens, patternToken{literal: s[i]})
+		case c == '*':
+			if charset == nil || charset.Len() == 0 {
+				return nil, errors.New("pattern uses '*' but charset is empty")
+			}
+			tokens = append(tokens, patternToken{charset: charset})
+		case patternClasses[c] != nil:
+			tokens = append(tokens, patternToken{charset: patternClasses[c]})
+		default:
+			tokens = append(tokens, patternToken{literal: c})
+		}
+	}
+
+	if len(tokens) > maxStringLength {
+		return nil, fmt.Errorf("pattern too long: %d (max %d)", len(tokens), maxStringLength)
+	}
+
+	return &Pattern{source: s, tokens: tokens}, nil
+}
+
+// Len returns the length of strings produced by the pattern
+func (p *Pattern) Len() int {
+	return len(p.tokens)
+}
+
+// Entropy returns the entropy in bits of strings produced by the pattern
+func (p *Pattern) Entropy() float64 {
+	var bits float64
+	for _, tok := range p.tokens {
+		if tok.charset != nil {
+			bits += math.Log2(float64(tok.charset.Len()))
+		}
+	}
+	return bits
+}
+
+// String returns the pattern source
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// generatePatternString generates a string following the configured pattern,
+// sampling every placeholder position uniformly
+func (cg *CryptoGenerator) generatePatternString(ctx context.Context, config *GeneratorConfig) (string, error) {
+	var sb strings.Builder
+	sb.Grow(config.Pattern.Len())
+
+	size := 0
+	for _, tok := range config.Pattern.tokens {
+		if tok.charset != nil {
+			size += sampleBytes(uint64(tok.charset.Len()), 1)
+		}
+	}
+	sampler := newIndexSampler(cg.entropy, size)
+	defer sampler.wipe()
+
+	for i, tok := range config.Pattern.tokens {
+		if tok.charset == nil {
+			sb.WriteByte(tok.literal)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		default:
+		}
+
+		idx, err := sampler.index(uint64(tok.charset.Len()))
+		if err != nil {
+			return "", fmt.Errorf("generating position %d: %w", i, err)
+		}
+		sb.WriteByte(tok.charset.At(idx))
+	}
+
+	return sb.String(), nil
+}
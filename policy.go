@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Rejection sampling limits. A policy draws enough candidates to fail with
+// probability below 2^-policyFailureBits; policies that would need more
+// than maxPolicyAttempts are rejected as too restrictive.
+const (
+	policyFailureBits = 40
+	maxPolicyAttempts = 100_000
+)
+
+// CharClass identifies one of the character classes tracked by a policy
+type CharClass uint8
+
+// Character classes recognized by PasswordPolicy
+const (
+	ClassUpper CharClass = iota
+	ClassLower
+	ClassDigit
+	ClassSymbol
+	numCharClasses
+)
+
+// String implements fmt.Stringer for CharClass
+func (c CharClass) String() string {
+	switch c {
+	case ClassUpper:
+		return "upper"
+	case ClassLower:
+		return "lower"
+	case ClassDigit:
+		return "digit"
+	case ClassSymbol:
+		return "symbol"
+	default:
+		return "unknown"
+	}
+}
+
+// classOf returns the character class of c
+func classOf(c byte) CharClass {
+	switch {
+	case c >= 'A' && c <= 'Z':
+		return ClassUpper
+	case c >= 'a' && c <= 'z':
+		return ClassLower
+	case c >= '0' && c <= '9':
+		return ClassDigit
+	default:
+		return ClassSymbol
+	}
+}
+
+// PasswordPolicy describes composition rules a generated string must satisfy.
+// The zero value imposes no constraints.
+type PasswordPolicy struct {
+	MinUpper  int
+	MinLower  int
+	MinDigit  int
+	MinSymbol int
+	Forbidden string // Characters removed from the charset
+	MaxRepeat int    // Maximum run of identical characters (0 = unlimited)
+}
+
+// IsZero reports whether the policy imposes no constraints
+func (p PasswordPolicy) IsZero() bool {
+	return p == PasswordPolicy{}
+}
+
+// minimums returns the per-class minimum counts indexed by CharClass
+func (p PasswordPolicy) minimums() [numCharClasses]int {
+	return [numCharClasses]int{p.MinUpper, p.MinLower, p.MinDigit, p.MinSymbol}
+}
+
+// Apply returns the character set with forbidden characters removed
+func (p PasswordPolicy) Apply(charset *CharacterSet) *CharacterSet {
+	if p.Forbidden == "" || charset == nil {
+		return charset
+	}
+	return charset.Without(p.Forbidden)
+}
+
+// Validate checks that the policy can be satisfied by strings of the given
+// length drawn from charset
+func (p PasswordPolicy) Validate(length int, charset *CharacterSet) error {
+	var errs []error
+
+	mins := p.minimums()
+	total := 0
+	for class, n := range mins {
+		if n < 0 {
+			errs = append(errs, fmt.Errorf("invalid minimum %s count: %d", CharClass(class), n))
+		}
+		total += n
+	}
+
+	if total > length {
+		errs = append(errs, fmt.Errorf("policy minimums (%d) exceed length %d", total, length))
+	}
+
+	if p.MaxRepeat < 0 {
+		errs = append(errs, fmt.Errorf("invalid max repeat: %d", p.MaxRepeat))
+	}
+
+	effective := p.Apply(charset)
+	if effective == nil || effective.Len() == 0 {
+		errs = append(errs, errors.New("charset is empty after removing forbidden characters"))
+		return errors.Join(errs...)
+	}
+
+	var available [numCharClasses]int
+	for _, c := range effective.chars {
+		available[classOf(c)]++
+	}
+	for class, n := range mins {
+		if n > 0 && available[class] == 0 {
+			errs = append(errs, fmt.Errorf("policy requires %s characters but charset has none", CharClass(class)))
+		}
+	}
+
+	if p.MaxRepeat > 0 && effective.Len() == 1 && length > p.MaxRepeat {
+		errs = append(errs, fmt.Errorf("cannot avoid runs longer than %d with a single-character charset", p.MaxRepeat))
+	}
+
+	if len(errs) == 0 && p.attempts(length, effective) == 0 {
+		errs = append(errs, fmt.Errorf("policy too restrictive: about 1 in %.3g candidates of length %d satisfies it", 1/p.cachedAcceptance(length, effective), length))
+	}
+
+	return errors.Join(errs...)
+}
+
+// attempts returns the number of candidates of the given length over charset,
+// with forbidden characters already removed, to draw before giving up, or 0
+// when the policy needs more than maxPolicyAttempts
+func (p PasswordPolicy) attempts(length int, charset *CharacterSet) int {
+	prob := p.cachedAcceptance(length, charset)
+	if prob >= 1 {
+		return 1
+	}
+	if prob <= 0 {
+		return 0
+	}
+	n := math.Ceil(policyFailureBits * math.Ln2 / -math.Log1p(-prob))
+	if n > maxPolicyAttempts {
+		return 0
+	}
+	return int(n)
+}
+
+// Satisfied reports whether s meets the policy
+func (p PasswordPolicy) Satisfied(s string) bool {
+	var counts [numCharClasses]int
+	run := 0
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(p.Forbidden, s[i]) >= 0 {
+			return false
+		}
+		counts[classOf(s[i])]++
+
+		if i > 0 && s[i] == s[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		if p.MaxRepeat > 0 && run > p.MaxRepeat {
+			return false
+		}
+	}
+
+	for class, n := range p.minimums() {
+		if counts[class] < n {
+			return false
+		}
+	}
+	return true
+}
+
+// generatePolicyString draws uniform candidates until one satisfies the
+// policy. Rejecting whole candidates keeps the output uniformly distributed
+// over the set of compliant strings.
+func (cg *CryptoGenerator) generatePolicyString(ctx context.Context, length int, charset *CharacterSet, policy PasswordPolicy) (string, error) {
+	if policy.IsZero() {
+		return cg.generateSecureString(ctx, length, charset)
+	}
+
+	charset = policy.Apply(charset)
+	attempts := policy.attempts(length, charset)
+	for range attempts {
+		candidate, err := cg.generateSecureString(ctx, length, charset)
+		if err != nil {
+			return "", err
+		}
+		if policy.Satisfied(candidate) {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("no string satisfying the policy after %d attempts - policy too restrictive", attempts)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// maxBigRetries bounds rejection sampling of big integers. Each draw is
+// accepted with probability above 1/2, so failure is negligible.
+const maxBigRetries = 64
+
+// Syllable units for pronounceable strings. Onsets contain only consonants
+// and nuclei only vowels, so every output decodes to exactly one sequence of
+// units and the entropy is the log of the number of possible outputs.
+var (
+	pronounceableOnsets = []string{
+		"b", "c", "d", "f", "g", "h", "j", "k", "l", "m", "n", "p", "r", "s", "t", "v", "w", "z",
+		"bl", "br", "ch", "cl", "cr", "dr", "fl", "fr", "gl", "gr", "pl", "pr",
+		"sh", "sk", "sl", "sm", "sn", "sp", "st", "sw", "th", "tr",
+	}
+	pronounceableNuclei = []string{
+		"a", "e", "i", "o", "u",
+		"ai", "au", "ea", "ee", "oa", "oo", "ou",
+	}
+)
+
+// pronounceableCounts memoizes completion counts per length
+var pronounceableCounts sync.Map // int -> [2][]*big.Int
+
+// pronounceableTable returns the number of ways to complete n characters
+// when the next unit is an onset (index 0) or a nucleus (index 1), for every
+// n up to length
+func pronounceableTable(length int) [2][]*big.Int {
+	if cached, ok := pronounceableCounts.Load(length); ok {
+		return cached.([2][]*big.Int)
+	}
+
+	units := [2][]string{pronounceableOnsets, pronounceableNuclei}
+	var table [2][]*big.Int
+	for s := range table {
+		table[s] = make([]*big.Int, length+1)
+		table[s][0] = big.NewInt(1)
+	}
+
+	for n := 1; n <= length; n++ {
+		for s := range table {
+			count := new(big.Int)
+			for _, u := range units[s] {
+				if len(u) <= n {
+					count.Add(count, table[1-s][n-len(u)])
+				}
+			}
+			table[s][n] = count
+		}
+	}
+
+	cached, _ := pronounceableCounts.LoadOrStore(length, table)
+	return cached.([2][]*big.Int)
+}
+
+// pronounceableEntropy returns the entropy of uniformly chosen pronounceable
+// strings of the given length
+func pronounceableEntropy(length int) float64 {
+	if length <= 0 {
+		return 0
+	}
+	total := pronounceableTable(length)[0][length]
+	return bigLog2(total)
+}
+
+// bigLog2 returns log2(x) for a positive big integer
+func bigLog2(x *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(x).Float64()
+	if !math.IsInf(f, 0) {
+		return math.Log2(f)
+	}
+
+	// Shift down to float range and add the shifted bits back
+	shift := x.BitLen() - 64
+	f, _ = new(big.Float).SetInt(new(big.Int).Rsh(x, uint(shift))).Float64()
+	return math.Log2(f) + float64(shift)
+}
+
+// generatePronounceable generates a string of alternating consonant and
+// vowel units, chosen uniformly among all such strings of the configured
+// length
+func (cg *CryptoGenerator) generatePronounceable(ctx context.Context, config *GeneratorConfig) (string, error) {
+	table := pronounceableTable(config.Length)
+
+	r, err := cg.uniformBigInt(ctx, table[0][config.Length])
+	if err != nil {
+		return "", err
+	}
+
+	// Unrank r into a unit sequence
+	units := [2][]string{pronounceableOnsets, pronounceableNuclei}
+	var sb strings.Builder
+	sb.Grow(config.Length)
+	count := new(big.Int)
+	for n, s := config.Length, 0; n > 0; s = 1 - s {
+		for _, u := range units[s] {
+			if len(u) > n {
+				continue
+			}
+			count.Set(table[1-s][n-len(u)])
+			if r.Cmp(count) < 0 {
+				sb.WriteString(u)
+				n -= len(u)
+				break
+			}
+			r.Sub(r, count)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// uniformBigInt returns a uniformly distributed big integer in [0, n) using
+// rejection sampling over masked entropy bytes
+func (cg *CryptoGenerator) uniformBigInt(ctx context.Context, n *big.Int) (*big.Int, error) {
+	if n.Sign() <= 0 {
+		return nil, errors.New("cannot sample from an empty range")
+	}
+
+	bits := n.BitLen()
+	size := (bits + 7) / 8
+	topMask := byte(0xff >> (8*size - bits))
+
+	for range maxBigRetries {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		buf, err := cg.entropy.GenerateBytes(size)
+		if err != nil {
+			return nil, fmt.Errorf("generating random value: %w", err)
+		}
+		buf[0] &= topMask
+
+		r := new(big.Int).SetBytes(buf)
+		clear(buf)
+		if r.Cmp(n) < 0 {
+			return r, nil
+		}
+	}
+
+	return nil, errors.New("too many retries in random sampling - possible attack")
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/spf13/cobra"
+)
+
+// Renderer fills a text/template with freshly generated secrets. Named
+// secrets are generated once per render, so every reference to the same
+// name produces the same value.
+type Renderer struct {
+	ctx       context.Context
+	generator *CryptoGenerator
+	secrets   map[string]string
+}
+
+// NewRenderer creates a renderer generating values with gen
+func NewRenderer(ctx context.Context, gen *CryptoGenerator) *Renderer {
+	return &Renderer{ctx: ctx, generator: gen, secrets: make(map[string]string)}
+}
+
+// Funcs returns the template functions:
+//
+//	password LENGTH [CHARSET]   compact password, alnum by default
+//	passphrase WORDS [SEP]      passphrase from the EFF wordlist
+//	hex BYTES                   random bytes, hex encoded
+//	uuid                        random (v4) UUID
+//	secret NAME [VALUE]         value shared by every reference to NAME
+//
+// The first reference to a secret fixes its value: VALUE when given, such as
+// (password 32), or a default hyphenated password.
+func (r *Renderer) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"password":   r.password,
+		"passphrase": r.passphrase,
+		"hex":        r.hex,
+		"uuid":       r.uuid,
+		"secret":     r.secret,
+	}
+}
+
+// generate generates one value from an entry holding the bundle defaults
+func (r *Renderer) generate(entry BundleEntry) (string, error) {
+	config, err := entry.Config()
+	if err != nil {
+		return "", err
+	}
+	return r.generator.Generate(r.ctx, config)
+}
+
+func (r *Renderer) password(length int, charset ...string) (string, error) {
+	entry := defaultBundleEntry()
+	entry.Type = "compact"
+	entry.Length = &length
+	switch len(charset) {
+	case 0:
+	case 1:
+		entry.Charset = charset[0]
+	default:
+		return "", errors.New("password takes a length and an optional charset")
+	}
+	return r.generate(entry)
+}
+
+func (r *Renderer) passphrase(words int, separator ...string) (string, error) {
+	entry := defaultBundleEntry()
+	entry.Type = "passphrase"
+	entry.Words = words
+	switch len(separator) {
+	case 0:
+	case 1:
+		entry.Separator = separator[0]
+	default:
+		return "", errors.New("passphrase takes a word count and an optional separator")
+	}
+	return r.generate(entry)
+}
+
+func (r *Renderer) hex(n int) (string, error) {
+	entry := defaultBundleEntry()
+	entry.Type = "token"
+	entry.Bits = n * 8
+	entry.Encoding = "hex"
+	return r.generate(entry)
+}
+
+func (r *Renderer) uuid() (string, error) {
+	entry := defaultBundleEntry()
+	entry.Type = "uuid4"
+	return r.generate(entry)
+}
+
+func (r *Renderer) secret(name string, value ...string) (string, error) {
+	if len(value) > 1 {
+		return "", errors.New("secret takes a name and an optional value")
+	}
+	if name == "" {
+		return "", errors.New("secret name cannot be empty")
+	}
+	if v, ok := r.secrets[name]; ok {
+		return v, nil
+	}
+
+	var v string
+	if len(value) == 1 {
+		v = value[0]
+	} else {
+		var err error
+		if v, err = r.generate(defaultBundleEntry()); err != nil {
+			return "", err
+		}
+	}
+	r.secrets[name] = v
+	return v, nil
+}
+
+// Render executes the template text named name and writes the result to w.
+// Nothing is written unless the whole template renders.
+func (r *Renderer) Render(w io.Writer, name, text string) error {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(r.Funcs()).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	defer func() { clear(buf.Bytes()) }()
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// newRenderCommand creates the render subcommand, which fills a config file
+// template with generated secrets
+func (app *Application) newRenderCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render TEMPLATE",
+		Short: "Fill a config file template with generated secrets",
+		Long: `Render a Go text/template, replacing placeholders with fresh secrets:
+
+  {{ password 24 "alnum" }}   compact password (charset optional, alnum by default)
+  {{ passphrase 6 }}          passphrase (separator optional)
+  {{ hex 32 }}                32 random bytes, hex encoded
+  {{ uuid }}                  random UUID
+  {{ secret "db" }}           the same value for every reference to "db"
+
+The first reference to a secret may choose how it is generated, for example
+{{ secret "db" (password 32) }}; later references repeat its value. Pass "-"
+to read the template from stdin. Nothing is written unless the whole
+template renders.`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         app.runRender,
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Write to this file (mode 0600) instead of stdout")
+	return cmd
+}
+
+// runRender executes the render subcommand
+func (app *Application) runRender(cmd *cobra.Command, args []string) error {
+	var (
+		text []byte
+		err  error
+	)
+	name := args[0]
+	if name == "-" {
+		text, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		text, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return fmt.Errorf("reading template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	defer func() { clear(buf.Bytes()) }()
+	renderer := NewRenderer(cmd.Context(), app.generator)
+	if err := renderer.Render(&buf, filepath.Base(name), string(text)); err != nil {
+		return err
+	}
+
+	if path, _ := cmd.Flags().GetString("output"); path != "" {
+		if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+			return fmt.Errorf("writing rendered template: %w", err)
+		}
+		return nil
+	}
+	_, err = cmd.OutOrStdout().Write(buf.Bytes())
+	return err
+}
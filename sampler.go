@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+// Sampling parameters
+const (
+	maxSampleChunk   = 4096 // Largest entropy read per refill
+	sampleSlack      = 8    // Extra bytes read up front to absorb unlucky rejections
+	maxSampleRetries = 64   // A bit-level draw is rejected with probability below 1/2
+)
+
+// indexSampler draws uniform indices from entropy read in chunks. Each draw
+// consumes only ceil(log2(n)) bits, e.g. 6 for a 64-character set, and
+// values at or above n are rejected and redrawn from fresh bits, so every
+// index is equally likely. A sampler is not safe for concurrent use; callers
+// create one per string.
+type indexSampler struct {
+	source EntropySource
+	chunk  int // Bytes per refill
+	buf    []byte
+	pos    int
+	acc    uint64 // Unconsumed bits, least significant first
+	nbits  uint
+}
+
+// newIndexSampler creates a sampler reading size bytes from source per
+// refill, clamped to 1-maxSampleChunk
+func newIndexSampler(source EntropySource, size int) *indexSampler {
+	if size < 1 {
+		size = 1
+	}
+	return &indexSampler{source: source, chunk: min(size, maxSampleChunk)}
+}
+
+// sampleBytes estimates the entropy in bytes consumed by draws indices in
+// [0, n): k bits per draw times the expected 2^k/n attempts, plus slack
+func sampleBytes(n uint64, draws int) int {
+	if n <= 1 || draws <= 0 {
+		return 0
+	}
+	k := bits.Len64(n - 1)
+	expected := float64(draws*k) * math.Ldexp(1, k) / float64(n)
+	return int(math.Ceil(expected/8)) + sampleSlack
+}
+
+// refill replaces the consumed chunk with a fresh read
+func (s *indexSampler) refill() error {
+	clear(s.buf)
+	buf, err := s.source.GenerateBytes(s.chunk)
+	if err != nil {
+		return fmt.Errorf("generating random value: %w", err)
+	}
+	s.buf, s.pos = buf, 0
+	return nil
+}
+
+// bits returns the next k random bits
+func (s *indexSampler) bits(k uint) (uint64, error) {
+	if k > 32 {
+		// Keep the accumulator from overflowing
+		hi, err := s.bits(k - 32)
+		if err != nil {
+			return 0, err
+		}
+		lo, err := s.bits(32)
+		return hi<<32 | lo, err
+	}
+
+	for s.nbits < k {
+		if s.pos == len(s.buf) {
+			if err := s.refill(); err != nil {
+				return 0, err
+			}
+		}
+		s.acc |= uint64(s.buf[s.pos]) << s.nbits
+		s.buf[s.pos] = 0
+		s.pos++
+		s.nbits += 8
+	}
+
+	v := s.acc & (1<<k - 1)
+	s.acc >>= k
+	s.nbits -= k
+	return v, nil
+}
+
+// index returns a uniformly distributed random index in [0, n)
+func (s *indexSampler) index(n uint64) (uint64, error) {
+	if n == 0 {
+		return 0, errors.New("cannot sample from an empty range")
+	}
+
+	k := uint(bits.Len64(n - 1))
+	for range maxSampleRetries {
+		v, err := s.bits(k)
+		if err != nil {
+			return 0, err
+		}
+		if v < n {
+			return v, nil
+		}
+	}
+
+	return 0, errors.New("too many retries in random sampling - possible attack")
+}
+
+// wipe clears the buffered entropy
+func (s *indexSampler) wipe() {
+	clear(s.buf)
+	s.buf, s.pos = nil, 0
+	s.acc, s.nbits = 0, 0
+}
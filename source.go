@@ -0,0 +1,369 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/chacha20"
+)
+
+// EntropySource provides cryptographically secure random bytes and reports
+// its health and usage. Health returns the reason the source failed, or nil
+// while it is healthy; Reset clears a failure. Implementations must be safe
+// for concurrent use. Sources holding resources such as open devices also
+// implement io.Closer.
+type EntropySource interface {
+	fmt.Stringer
+	GenerateBytes(n int) ([]byte, error)
+	GenerateUint64() (uint64, error)
+	Health() error
+	Reset() error
+	Stats() (generated, errors uint64)
+	Deterministic() bool // Output is reproducible from configuration alone
+}
+
+// MixMethod identifies how a mixing source combines its inputs
+type MixMethod uint8
+
+// Supported mixing methods
+const (
+	MixHash MixMethod = iota
+	MixXOR
+)
+
+// String implements fmt.Stringer for MixMethod
+func (m MixMethod) String() string {
+	switch m {
+	case MixHash:
+		return "hash"
+	case MixXOR:
+		return "xor"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseMixMethod parses a string into MixMethod
+func ParseMixMethod(s string) (MixMethod, error) {
+	switch strings.ToLower(s) {
+	case "hash", "sha256":
+		return MixHash, nil
+	case "xor":
+		return MixXOR, nil
+	default:
+		return 0, fmt.Errorf("invalid mix method: %q", s)
+	}
+}
+
+// sourceCore implements EntropySource on top of a fill function, tracking
+// statistics. A failed fill marks the source unhealthy until Reset.
+type sourceCore struct {
+	name          string
+	fill          func([]byte) error
+	deterministic bool
+	failure       atomic.Pointer[error]
+	stats         struct {
+		generated atomic.Uint64
+		errors    atomic.Uint64
+	}
+}
+
+// newSourceCore creates a healthy source named name that fills buffers
+// with fill
+func newSourceCore(name string, fill func([]byte) error) *sourceCore {
+	return &sourceCore{name: name, fill: fill}
+}
+
+// String implements fmt.Stringer
+func (sc *sourceCore) String() string {
+	return sc.name
+}
+
+// GenerateBytes generates n random bytes
+func (sc *sourceCore) GenerateBytes(n int) ([]byte, error) {
+	if err := sc.failure.Load(); err != nil {
+		return nil, fmt.Errorf("entropy source is unhealthy: %w", *err)
+	}
+
+	buf := make([]byte, n)
+	if err := sc.fill(buf); err != nil {
+		err = fmt.Errorf("failed to generate random bytes: %w", err)
+		sc.stats.errors.Add(1)
+		sc.failure.Store(&err)
+		return nil, err
+	}
+
+	sc.stats.generated.Add(uint64(n))
+	return buf, nil
+}
+
+// GenerateUint64 generates a random uint64
+func (sc *sourceCore) GenerateUint64() (uint64, error) {
+	return uint64FromSource(sc)
+}
+
+// Health returns the read failure that made the source unhealthy, if any
+func (sc *sourceCore) Health() error {
+	if err := sc.failure.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// Reset clears a read failure
+func (sc *sourceCore) Reset() error {
+	sc.failure.Store(nil)
+	return nil
+}
+
+// Stats returns statistics about the entropy source
+func (sc *sourceCore) Stats() (generated, errors uint64) {
+	return sc.stats.generated.Load(), sc.stats.errors.Load()
+}
+
+// Deterministic reports whether the source replays a fixed sequence
+func (sc *sourceCore) Deterministic() bool {
+	return sc.deterministic
+}
+
+// uint64FromSource reads a random uint64 from source
+func uint64FromSource(source EntropySource) (uint64, error) {
+	bytes, err := source.GenerateBytes(8)
+	if err != nil {
+		return 0, err
+	}
+
+	// Convert bytes to uint64 using safe binary encoding
+	return binary.LittleEndian.Uint64(bytes), nil
+}
+
+// NewEntropySource creates the default entropy source: crypto/rand behind
+// the SP 800-90B health tests with their default configuration
+func NewEntropySource() EntropySource {
+	source, err := NewHealthTestedSource(NewSystemEntropySource(), DefaultHealthConfig())
+	if err != nil {
+		panic(fmt.Sprintf("default health configuration is invalid: %v", err))
+	}
+	return source
+}
+
+// NewSystemEntropySource creates an entropy source reading crypto/rand
+// directly, without health tests
+func NewSystemEntropySource() EntropySource {
+	return newSourceCore("system", func(b []byte) error {
+		_, err := rand.Read(b)
+		return err
+	})
+}
+
+// fileSource reads entropy from a file or character device
+type fileSource struct {
+	*sourceCore
+	file *os.File
+}
+
+// NewFileEntropySource creates an entropy source reading from a file or
+// device such as /dev/hwrng. Short reads are retried; reaching the end of a
+// regular file is an error. The returned source implements io.Closer.
+func NewFileEntropySource(path string) (EntropySource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := &fileSource{file: f}
+	fs.sourceCore = newSourceCore("file:"+path, func(b []byte) error {
+		_, err := io.ReadFull(f, b)
+		return err
+	})
+	return fs, nil
+}
+
+// Close closes the underlying file
+func (fs *fileSource) Close() error {
+	return fs.file.Close()
+}
+
+// NewSeededEntropySource creates a deterministic entropy source producing the
+// ChaCha20 keystream keyed by the SHA-256 of seed. The same seed always
+// yields the same bytes, which makes it suitable for tests and fixtures
+// only: its output is exactly as secret as the seed.
+func NewSeededEntropySource(seed []byte) EntropySource {
+	key := sha256.Sum256(seed)
+	stream, err := chacha20.NewUnauthenticatedCipher(key[:], make([]byte, chacha20.NonceSize))
+	if err != nil {
+		panic(fmt.Sprintf("seeded entropy source: %v", err)) // Key and nonce sizes are constant
+	}
+
+	var mu sync.Mutex
+	sc := newSourceCore("seeded", func(b []byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		clear(b)
+		stream.XORKeyStream(b, b)
+		return nil
+	})
+	sc.deterministic = true
+	return sc
+}
+
+// mixingSource combines the output of several sources
+type mixingSource struct {
+	*sourceCore
+	sources []EntropySource
+}
+
+// NewMixingEntropySource creates an entropy source that combines every input
+// so that the output is unpredictable as long as any one input is. MixXOR
+// XORs equal-length reads; MixHash outputs SHA-256 over 32-byte reads from
+// each input per 32-byte block. A failure of any input fails the read.
+// Closing the returned source closes the inputs that implement io.Closer.
+func NewMixingEntropySource(method MixMethod, sources ...EntropySource) (EntropySource, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("mixing source needs at least one input")
+	}
+
+	names := make([]string, len(sources))
+	for i, src := range sources {
+		names[i] = src.String()
+	}
+	ms := &mixingSource{sources: sources}
+	name := fmt.Sprintf("mix-%s(%s)", method, strings.Join(names, ","))
+
+	switch method {
+	case MixXOR:
+		ms.sourceCore = newSourceCore(name, ms.fillXOR)
+	case MixHash:
+		ms.sourceCore = newSourceCore(name, ms.fillHash)
+	default:
+		return nil, fmt.Errorf("invalid mix method: %v", method)
+	}
+	return ms, nil
+}
+
+// fillXOR fills b with the XOR of equal-length reads from every input
+func (ms *mixingSource) fillXOR(b []byte) error {
+	clear(b)
+	for _, src := range ms.sources {
+		buf, err := src.GenerateBytes(len(b))
+		if err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+		for i := range b {
+			b[i] ^= buf[i]
+		}
+		clear(buf)
+	}
+	return nil
+}
+
+// fillHash fills b block by block with SHA-256 over a read from every input
+func (ms *mixingSource) fillHash(b []byte) error {
+	for off := 0; off < len(b); off += sha256.Size {
+		h := sha256.New()
+		for _, src := range ms.sources {
+			buf, err := src.GenerateBytes(sha256.Size)
+			if err != nil {
+				return fmt.Errorf("%s: %w", src, err)
+			}
+			h.Write(buf)
+			clear(buf)
+		}
+		copy(b[off:], h.Sum(nil))
+	}
+	return nil
+}
+
+// Deterministic reports whether every input is deterministic; a single
+// unpredictable input makes the mix unpredictable
+func (ms *mixingSource) Deterministic() bool {
+	for _, src := range ms.sources {
+		if !src.Deterministic() {
+			return false
+		}
+	}
+	return true
+}
+
+// Reset clears a failure of the mixing source and of every input
+func (ms *mixingSource) Reset() error {
+	errs := []error{ms.sourceCore.Reset()}
+	for _, src := range ms.sources {
+		errs = append(errs, src.Reset())
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes every input that implements io.Closer
+func (ms *mixingSource) Close() error {
+	var errs []error
+	for _, src := range ms.sources {
+		if c, ok := src.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ParseEntropySource builds an entropy source from a comma-separated list of
+// "system", "file:PATH" (alias "device:PATH") or "seed:TEXT" entries. Each
+// entry is health tested with the given configuration, and several entries
+// are combined with the given mixing method.
+func ParseEntropySource(spec string, method MixMethod, health HealthConfig) (EntropySource, error) {
+	var sources []EntropySource
+	fail := func(err error) (EntropySource, error) {
+		for _, src := range sources {
+			if c, ok := src.(io.Closer); ok {
+				c.Close()
+			}
+		}
+		return nil, err
+	}
+
+	for entry := range strings.SplitSeq(spec, ",") {
+		var raw EntropySource
+		kind, arg, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		switch strings.ToLower(kind) {
+		case "system":
+			raw = NewSystemEntropySource()
+		case "file", "device":
+			if arg == "" {
+				return fail(fmt.Errorf("entropy source %q needs a path", entry))
+			}
+			src, err := NewFileEntropySource(arg)
+			if err != nil {
+				return fail(fmt.Errorf("opening entropy source: %w", err))
+			}
+			raw = src
+		case "seed":
+			if arg == "" {
+				return fail(fmt.Errorf("entropy source %q needs a seed", entry))
+			}
+			raw = NewSeededEntropySource([]byte(arg))
+		default:
+			return fail(fmt.Errorf("invalid entropy source: %q", entry))
+		}
+
+		src, err := NewHealthTestedSource(raw, health)
+		if err != nil {
+			if c, ok := raw.(io.Closer); ok {
+				c.Close()
+			}
+			return fail(err)
+		}
+		sources = append(sources, src)
+	}
+
+	if len(sources) == 1 {
+		return sources[0], nil
+	}
+	return NewMixingEntropySource(method, sources...)
+}
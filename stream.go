@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Streaming parameters
+const (
+	streamWindowPerWorker = 64       // Results each worker may run ahead of the consumer
+	streamBufferSize      = 64 << 10 // Output buffer for streamed strings
+)
+
+// streamResult is a generated string tagged with its position in the stream
+type streamResult struct {
+	index int
+	value GeneratedString
+	err   error
+}
+
+// streamSequential generates the stream one string at a time on the
+// calling goroutine
+func (cg *CryptoGenerator) streamSequential(ctx context.Context, config *GeneratorConfig, yield func(GeneratedString, error) bool) {
+	for i := range config.Count {
+		if err := ctx.Err(); err != nil {
+			yield(GeneratedString{}, err)
+			return
+		}
+
+		result, err := cg.generateTimed(ctx, config, i)
+		if !yield(result, err) {
+			return
+		}
+	}
+}
+
+// streamParallel generates the stream with a pool of workers. A window of
+// slots bounds the strings generated but not yet consumed, so a slow
+// consumer stalls the workers instead of growing memory. Results are yielded
+// by position, holding early finishers back until their predecessors arrive,
+// unless Unordered is set, which yields them as soon as they are ready.
+func (cg *CryptoGenerator) streamParallel(ctx context.Context, config *GeneratorConfig, yield func(GeneratedString, error) bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait() // Runs after cancel, so every goroutine is gone on return
+	defer cancel()
+
+	slots := make(chan struct{}, config.Workers*streamWindowPerWorker)
+	jobs := make(chan int)
+	results := make(chan streamResult, config.Workers)
+
+	// Feed positions while the window has room
+	wg.Go(func() {
+		defer close(jobs)
+		for i := range config.Count {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+
+	var workers sync.WaitGroup
+	for range config.Workers {
+		workers.Go(func() {
+			for i := range jobs {
+				value, err := cg.generateTimed(ctx, config, i)
+				select {
+				case results <- streamResult{index: i, value: value, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		})
+	}
+	wg.Go(func() {
+		workers.Wait()
+		close(results)
+	})
+
+	emitted := 0
+	emit := func(r streamResult) bool {
+		<-slots
+		if err := ctx.Err(); err != nil {
+			yield(GeneratedString{}, err)
+			return false
+		}
+		emitted++
+		return yield(r.value, r.err)
+	}
+
+	pending := make(map[int]streamResult) // Ordered results waiting for a predecessor
+	next := 0
+	for r := range results {
+		if config.Unordered {
+			if !emit(r) {
+				return
+			}
+			continue
+		}
+
+		pending[r.index] = r
+		for {
+			r, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if !emit(r) {
+				return
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil && emitted < config.Count {
+		yield(GeneratedString{}, err)
+	}
+}
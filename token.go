@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Token size limits in bits
+const (
+	minTokenBits     = 8
+	maxTokenBits     = 4096
+	defaultTokenBits = 256
+)
+
+// base58Alphabet is the Bitcoin base58 alphabet
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// TokenEncoding represents how raw token bytes are rendered as text
+type TokenEncoding uint8
+
+// Supported token encodings
+const (
+	EncodingHex TokenEncoding = iota
+	EncodingBase32
+	EncodingBase32NoPad
+	EncodingBase58
+	EncodingBase64URL
+)
+
+// String implements fmt.Stringer for TokenEncoding
+func (e TokenEncoding) String() string {
+	switch e {
+	case EncodingHex:
+		return "hex"
+	case EncodingBase32:
+		return "base32"
+	case EncodingBase32NoPad:
+		return "base32-nopad"
+	case EncodingBase58:
+		return "base58"
+	case EncodingBase64URL:
+		return "base64url"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTokenEncoding parses a string into TokenEncoding
+func ParseTokenEncoding(s string) (TokenEncoding, error) {
+	switch strings.ToLower(s) {
+	case "hex":
+		return EncodingHex, nil
+	case "base32":
+		return EncodingBase32, nil
+	case "base32-nopad", "base32nopad":
+		return EncodingBase32NoPad, nil
+	case "base58":
+		return EncodingBase58, nil
+	case "base64url", "base64":
+		return EncodingBase64URL, nil
+	default:
+		return 0, fmt.Errorf("invalid token encoding: %q", s)
+	}
+}
+
+// EncodedLen returns the length of the encoding of n bytes. Every encoding
+// has a fixed length for a given input size.
+func (e TokenEncoding) EncodedLen(n int) int {
+	switch e {
+	case EncodingBase32:
+		return base32.StdEncoding.EncodedLen(n)
+	case EncodingBase32NoPad:
+		return base32.StdEncoding.WithPadding(base32.NoPadding).EncodedLen(n)
+	case EncodingBase58:
+		return int(math.Ceil(float64(8*n) / math.Log2(58)))
+	case EncodingBase64URL:
+		return base64.RawURLEncoding.EncodedLen(n)
+	default:
+		return hex.EncodedLen(n)
+	}
+}
+
+// Encode renders b in the encoding
+func (e TokenEncoding) Encode(b []byte) string {
+	switch e {
+	case EncodingBase32:
+		return base32.StdEncoding.EncodeToString(b)
+	case EncodingBase32NoPad:
+		return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+	case EncodingBase58:
+		return encodeBase58(b, e.EncodedLen(len(b)))
+	case EncodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString(b)
+	default:
+		return hex.EncodeToString(b)
+	}
+}
+
+// encodeBase58 encodes b as a big-endian number in the Bitcoin alphabet,
+// left-padded with the zero digit '1' to width characters so that token
+// length does not depend on the value
+func encodeBase58(b []byte, width int) string {
+	out := make([]byte, width)
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	for i := width - 1; i >= 0; i-- {
+		n.DivMod(n, radix, mod)
+		out[i] = base58Alphabet[mod.Int64()]
+	}
+	return string(out)
+}
+
+// generateToken draws the configured number of bits from the entropy source
+// and encodes them directly
+func (cg *CryptoGenerator) generateToken(ctx context.Context, config *GeneratorConfig) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	default:
+	}
+
+	raw, err := cg.entropy.GenerateBytes(config.Bits / 8)
+	if err != nil {
+		return "", fmt.Errorf("generating token bytes: %w", err)
+	}
+	defer clear(raw)
+
+	return config.Encoding.Encode(raw), nil
+}